@@ -0,0 +1,91 @@
+package woodcutter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// archiveDir returns the directory backups are kept in.
+func (l *Logger) archiveDir() string {
+	if l.ArchiveDir == "" {
+		return l.dir()
+	}
+	return filepath.Clean(l.ArchiveDir)
+}
+
+// backupDirs returns the directories that may hold backups: the log directory,
+// where backups are made, and the archive directory they are moved to.
+func (l *Logger) backupDirs() []string {
+	if archive := l.archiveDir(); archive != l.dir() {
+		return []string{l.dir(), archive}
+	}
+	return []string{l.dir()}
+}
+
+// archiveBackups moves the backups in the log directory to ArchiveDir, or to
+// their BackupDirLayout subdirectory, and sends EventBackupArchived for each.
+// It must be called with l.millMu held.
+func (l *Logger) archiveBackups() error {
+	if l.archiveDir() == l.dir() && l.BackupDirLayout == "" {
+		return nil
+	}
+	if _, err := l.partitionLayout(); err != nil {
+		return err
+	}
+
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return err
+	}
+	moved := map[string]bool{}
+	for _, f := range files {
+		dir := l.backupDir(f.timestamp)
+		if f.dir == dir {
+			continue
+		}
+		if !moved[dir] {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return fmt.Errorf("can't make archive directory: %w", err)
+			}
+			moved[dir] = true
+		}
+
+		src := f.path()
+		dst := filepath.Join(dir, f.Name())
+		info, err := f.Info()
+		if err != nil {
+			return fmt.Errorf("can't get backup info: %w", err)
+		}
+		if err := moveFile(src, dst, info, l.durable()); err != nil {
+			return err
+		}
+		if err := moveIndex(f, dst, l.durable()); err != nil {
+			return err
+		}
+		l.renameRotated(src, dst)
+		l.notePrunable(f.dir)
+		l.notify(Event{Kind: EventBackupArchived, Filename: src, Backup: dst, Size: info.Size()})
+	}
+	for dir := range moved {
+		if err := l.syncLogDir(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveFile renames src, described by info, to dst, copying it and removing the
+// original if they are on different filesystems.
+func moveFile(src, dst string, info os.FileInfo, durable bool) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyFile(src, dst, info, durable); err != nil {
+		return err
+	}
+	if err := os.Remove(src); err != nil {
+		return fmt.Errorf("can't remove archived backup: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package woodcutter
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchive_MoveBackup(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive")
+
+	rec := &eventRecorder{}
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:   filename,
+		ArchiveDir: archive,
+		OnEvent:    rec.onEvent,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	// we need to wait a little bit since the backup is moved on a different
+	// goroutine.
+	<-time.After(300 * time.Millisecond)
+
+	// the log file and the archive directory.
+	fileCount(t, dir, 2)
+	fileCount(t, archive, 1)
+	fileContainsContent(t, backupFile(archive), b)
+
+	e, ok := rec.find(EventBackupArchived)
+	assert.True(t, ok)
+	assert.Equal(t, backupFile(dir), e.Filename)
+	assert.Equal(t, backupFile(archive), e.Backup)
+	assert.Equal(t, int64(len(b)), e.Size)
+}
+
+func TestArchive_MaxBackups(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive")
+	err := os.Mkdir(archive, 0o755)
+	assert.Nil(t, err)
+
+	// an archived backup and one left in the log directory.
+	data := []byte("data")
+	err = os.WriteFile(backupFile(archive), data, 0o644)
+	assert.Nil(t, err)
+	newFakeTime()
+	err = os.WriteFile(backupFile(dir), data, 0o644)
+	assert.Nil(t, err)
+	newFakeTime()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:   filename,
+		ArchiveDir: archive,
+		MaxBackups: 2,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	<-time.After(300 * time.Millisecond)
+
+	// the oldest backup is removed, wherever it is, and the newer ones end up
+	// in the archive.
+	fileCount(t, dir, 2)
+	fileCount(t, archive, 2)
+	fileContainsContent(t, backupFile(archive), b)
+}
+
+func TestArchive_Compress(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive")
+
+	var (
+		mu      sync.Mutex
+		rotated []RotatedFile
+	)
+	l := &Logger{
+		Filename:   logFile(dir),
+		ArchiveDir: archive,
+		Compress:   true,
+		OnRotate: func(file RotatedFile) error {
+			mu.Lock()
+			defer mu.Unlock()
+			rotated = append(rotated, file)
+			return nil
+		},
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	<-time.After(300 * time.Millisecond)
+
+	fileCount(t, archive, 1)
+	assert.FileExists(t, backupFile(archive)+compressSuffix)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, len(rotated))
+	assert.Equal(t, backupFile(archive), rotated[0].Backup)
+	assert.Equal(t, backupFile(archive)+compressSuffix, rotated[0].Final)
+}
+
+func TestArchive_MoveFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.log")
+	dst := filepath.Join(dir, "dst.log")
+
+	data := []byte("data")
+	err := os.WriteFile(src, data, 0o600)
+	assert.Nil(t, err)
+	info, err := os.Stat(src)
+	assert.Nil(t, err)
+
+	err = copyFile(src, dst, info, true)
+	assert.Nil(t, err)
+	fileContainsContent(t, dst, data)
+
+	dstInfo, err := os.Stat(dst)
+	assert.Nil(t, err)
+	assert.Equal(t, info.Mode(), dstInfo.Mode())
+
+	err = moveFile(dst, filepath.Join(dir, "moved.log"), dstInfo, false)
+	assert.Nil(t, err)
+	assert.NoFileExists(t, dst)
+	fileContainsContent(t, filepath.Join(dir, "moved.log"), data)
+}
@@ -0,0 +1,203 @@
+package woodcutter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// defaultAsyncCapacity is the number of records an AsyncWriter queues when no
+// capacity is given.
+const defaultAsyncCapacity = 1024
+
+// ErrAsyncWriterClosed is returned by AsyncWriter.Write after Close.
+var ErrAsyncWriterClosed = errors.New("write to closed async writer")
+
+// ensure we always implement io.WriteCloser.
+var _ io.WriteCloser = (*AsyncWriter)(nil)
+
+// OverflowPolicy determines what an AsyncWriter does with a write when its
+// queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes Write wait until there is room in the queue.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropNewest discards the record being written.
+	OverflowDropNewest
+
+	// OverflowDropOldest discards the oldest queued record to make room.
+	OverflowDropOldest
+)
+
+// AsyncStats reports the state of an AsyncWriter.
+type AsyncStats struct {
+	// Queued is the number of records waiting to be written.
+	Queued int
+
+	// DroppedRecords is the number of records discarded because the queue
+	// was full.
+	DroppedRecords int64
+
+	// DroppedBytes is the total size of the discarded records.
+	DroppedBytes int64
+}
+
+// AsyncWriter is an io.WriteCloser that queues writes in a bounded ring
+// buffer and writes them to a Logger from a background goroutine, so that
+// callers are not held up by a slow disk.  Each call to Write is queued as one
+// record and passed to the Logger as one write.  Errors from the Logger are
+// passed to its OnError.
+type AsyncWriter struct {
+	logger   *Logger
+	overflow OverflowPolicy
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	records [][]byte
+	head    int
+	count   int
+	busy    bool
+	closed  bool
+	done    chan struct{}
+
+	droppedRecords int64
+	droppedBytes   int64
+}
+
+// NewAsyncWriter returns an AsyncWriter that queues up to capacity records for
+// l, handling a full queue according to overflow.  A capacity of 0 or less
+// means 1024 records.
+func NewAsyncWriter(l *Logger, capacity int, overflow OverflowPolicy) *AsyncWriter {
+	if capacity <= 0 {
+		capacity = defaultAsyncCapacity
+	}
+	a := &AsyncWriter{
+		logger:   l,
+		overflow: overflow,
+		records:  make([][]byte, capacity),
+		done:     make(chan struct{}),
+	}
+	a.cond = sync.NewCond(&a.mu)
+	go a.run()
+	return a
+}
+
+// Write implements io.Writer.  It queues a copy of p and returns without
+// waiting for it to be written, unless the queue is full and the overflow
+// policy is OverflowBlock.  Dropped records are reported as written.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return 0, ErrAsyncWriterClosed
+	}
+
+	if a.count == len(a.records) {
+		switch a.overflow {
+		case OverflowDropNewest:
+			a.drop(p)
+			return len(p), nil
+		case OverflowDropOldest:
+			a.drop(a.pop())
+		default:
+			for a.count == len(a.records) && !a.closed {
+				a.cond.Wait()
+			}
+			if a.closed {
+				return 0, ErrAsyncWriterClosed
+			}
+		}
+	}
+
+	record := make([]byte, len(p))
+	copy(record, p)
+	a.records[(a.head+a.count)%len(a.records)] = record
+	a.count++
+	a.cond.Broadcast()
+	return len(p), nil
+}
+
+// Flush waits until the queued records have been written to the Logger, and
+// flushes the Logger.
+func (a *AsyncWriter) Flush() error {
+	a.mu.Lock()
+	for a.count > 0 || a.busy {
+		a.cond.Wait()
+	}
+	a.mu.Unlock()
+	return a.logger.Flush()
+}
+
+// Close stops accepting writes, waits until the queued records have been
+// written, and closes the Logger.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	a.closed = true
+	a.cond.Broadcast()
+	a.mu.Unlock()
+
+	<-a.done
+	return a.logger.Close()
+}
+
+// Stats returns the number of queued records and the records dropped so far.
+func (a *AsyncWriter) Stats() AsyncStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return AsyncStats{
+		Queued:         a.count,
+		DroppedRecords: a.droppedRecords,
+		DroppedBytes:   a.droppedBytes,
+	}
+}
+
+// pop removes and returns the oldest queued record.  It must be called with
+// a.mu held and a non-empty queue.
+func (a *AsyncWriter) pop() []byte {
+	record := a.records[a.head]
+	a.records[a.head] = nil
+	a.head = (a.head + 1) % len(a.records)
+	a.count--
+	return record
+}
+
+// drop counts record as dropped.
+func (a *AsyncWriter) drop(record []byte) {
+	a.droppedRecords++
+	a.droppedBytes += int64(len(record))
+}
+
+// run runs in a goroutine and writes the queued records to the Logger until
+// the AsyncWriter is closed and the queue is empty.
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for {
+		for a.count == 0 && !a.closed {
+			a.cond.Wait()
+		}
+		if a.count == 0 {
+			return
+		}
+
+		record := a.pop()
+		a.busy = true
+		a.cond.Broadcast()
+		a.mu.Unlock()
+
+		if _, err := a.logger.Write(record); err != nil {
+			a.logger.report(fmt.Errorf("can't write queued record: %w", err))
+			a.logger.deliver()
+		}
+
+		a.mu.Lock()
+		a.busy = false
+		a.cond.Broadcast()
+	}
+}
@@ -0,0 +1,131 @@
+package woodcutter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stallAsync makes the AsyncWriter's goroutine pick up a first record and get
+// stuck writing it until the returned function is called, so that the queue
+// fills up.
+func stallAsync(t *testing.T, a *AsyncWriter, l *Logger, first []byte) func() {
+	t.Helper()
+	l.mu.Lock()
+	n, err := a.Write(first)
+	assert.Nil(t, err)
+	assert.Equal(t, len(first), n)
+	for a.Stats().Queued > 0 {
+		<-time.After(time.Millisecond)
+	}
+	return l.mu.Unlock
+}
+
+func TestAsync_Write(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename}
+	a := NewAsyncWriter(l, 0, OverflowBlock)
+
+	for _, s := range []string{"boo!", "foo!", "baz!"} {
+		n, err := a.Write([]byte(s))
+		assert.Nil(t, err)
+		assert.Equal(t, len(s), n)
+	}
+	err := a.Flush()
+	assert.Nil(t, err)
+	fileContainsContent(t, filename, []byte("boo!foo!baz!"))
+
+	err = a.Close()
+	assert.Nil(t, err)
+	n, err := a.Write([]byte("late"))
+	assert.Equal(t, ErrAsyncWriterClosed, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestAsync_DropNewest(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename}
+	a := NewAsyncWriter(l, 2, OverflowDropNewest)
+	unstall := stallAsync(t, a, l, []byte("a"))
+
+	for _, s := range []string{"b", "c", "dd"} {
+		n, err := a.Write([]byte(s))
+		assert.Nil(t, err)
+		assert.Equal(t, len(s), n)
+	}
+	assert.Equal(t, AsyncStats{Queued: 2, DroppedRecords: 1, DroppedBytes: 2}, a.Stats())
+
+	unstall()
+	err := a.Close()
+	assert.Nil(t, err)
+	fileContainsContent(t, filename, []byte("abc"))
+	assert.Equal(t, AsyncStats{DroppedRecords: 1, DroppedBytes: 2}, a.Stats())
+}
+
+func TestAsync_DropOldest(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename}
+	a := NewAsyncWriter(l, 2, OverflowDropOldest)
+	unstall := stallAsync(t, a, l, []byte("a"))
+
+	for _, s := range []string{"bb", "c", "d"} {
+		n, err := a.Write([]byte(s))
+		assert.Nil(t, err)
+		assert.Equal(t, len(s), n)
+	}
+	assert.Equal(t, AsyncStats{Queued: 2, DroppedRecords: 1, DroppedBytes: 2}, a.Stats())
+
+	unstall()
+	err := a.Close()
+	assert.Nil(t, err)
+	fileContainsContent(t, filename, []byte("acd"))
+}
+
+func TestAsync_Block(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename}
+	a := NewAsyncWriter(l, 1, OverflowBlock)
+	unstall := stallAsync(t, a, l, []byte("a"))
+
+	n, err := a.Write([]byte("b"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		n, err := a.Write([]byte("c"))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, n)
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("write didn't block on a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unstall()
+	<-written
+	err = a.Close()
+	assert.Nil(t, err)
+	fileContainsContent(t, filename, []byte("abc"))
+	assert.Equal(t, AsyncStats{}, a.Stats())
+}
@@ -0,0 +1,71 @@
+package woodcutter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// defaultFlushInterval is how often buffered data is flushed when
+// FlushInterval is not set.
+const defaultFlushInterval = time.Second
+
+// Flush writes any data buffered because of BufferSize to the log file.  It
+// does nothing if the Logger is not buffered.
+func (l *Logger) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.flush()
+}
+
+// flush writes the buffered data to the current file, if any.
+func (l *Logger) flush() error {
+	if l.buf == nil || l.file == nil {
+		return nil
+	}
+	if err := l.buf.Flush(); err != nil {
+		return fmt.Errorf("can't flush log file: %w", err)
+	}
+	return nil
+}
+
+// setFile makes f the current log file, wrapping it in a buffer if BufferSize
+// is set, and starts the background goroutine if it has to be flushed, synced
+// or checked periodically.
+func (l *Logger) setFile(f *os.File) {
+	l.file = f
+	switch {
+	case l.BufferSize <= 0:
+		l.buf = nil
+	case l.buf == nil || l.buf.Size() != l.BufferSize:
+		l.buf = bufio.NewWriterSize(f, l.BufferSize)
+	default:
+		l.buf.Reset(f)
+	}
+	if l.flushInterval() > 0 || l.Durability == DurabilityInterval || l.ReopenInterval > 0 {
+		l.startBackground()
+	}
+}
+
+// output returns the writer that data for the current file goes to.
+func (l *Logger) output() io.Writer {
+	if l.buf != nil {
+		return l.buf
+	}
+	return l.file
+}
+
+// flushInterval returns how often buffered data is flushed in the background,
+// or 0 if it is only flushed when the buffer fills up.
+func (l *Logger) flushInterval() time.Duration {
+	switch {
+	case l.BufferSize <= 0 || l.FlushInterval < 0:
+		return 0
+	case l.FlushInterval == 0:
+		return defaultFlushInterval
+	default:
+		return l.FlushInterval
+	}
+}
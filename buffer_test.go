@@ -0,0 +1,117 @@
+package woodcutter
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuffer_Flush(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:      filename,
+		BufferSize:    1024,
+		FlushInterval: -1,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+	info, err := os.Stat(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), info.Size())
+
+	err = l.Flush()
+	assert.Nil(t, err)
+	fileContainsContent(t, filename, b)
+}
+
+func TestBuffer_FlushOnRotateAndClose(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:      filename,
+		MaxSize:       10,
+		BufferSize:    1024,
+		FlushInterval: -1,
+	}
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	// buffered bytes count towards MaxSize, so this rotates.
+	newFakeTime()
+	b2 := []byte("foooooo!")
+	n, err = l.Write(b2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b2), n)
+
+	fileContainsContent(t, backupFile(dir), b)
+	info, err := os.Stat(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), info.Size())
+
+	err = l.Close()
+	assert.Nil(t, err)
+	fileContainsContent(t, filename, b2)
+	fileCount(t, dir, 2)
+}
+
+func TestBuffer_PeriodicFlush(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:      filename,
+		BufferSize:    1024,
+		FlushInterval: 10 * time.Millisecond,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	// we need to wait a little bit since the flush happens on a different
+	// goroutine.
+	<-time.After(100 * time.Millisecond)
+	fileContainsContent(t, filename, b)
+}
+
+func TestBuffer_LargeWrite(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:      filename,
+		BufferSize:    16,
+		FlushInterval: -1,
+	}
+	defer l.Close()
+
+	// writes that don't fit in the buffer go to the file.
+	b := []byte("more than sixteen bytes")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+	fileContainsContent(t, filename, b)
+}
@@ -0,0 +1,119 @@
+// Command woodcutter writes its standard input to a log file rotated by a
+// woodcutter.Logger, like rotatelogs or multilog, for programs that can only
+// log to their standard output:
+//
+//	myprogram | woodcutter -max-size 100 -max-backups 10 -compress /var/log/myprogram.log
+//
+// Records are only split between files at line boundaries.  SIGHUP rotates
+// the log file, and SIGINT or SIGTERM, like the end of the input, closes it
+// cleanly.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Rajil1213/woodcutter"
+)
+
+func main() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, sigs))
+}
+
+// run pipes stdin into the Logger configured by args until stdin ends or a
+// signal asks to stop, and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer, sigs <-chan os.Signal) int {
+	l, tee, err := newLogger(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "woodcutter: %v\n", err)
+		return 2
+	}
+	l.OnError = func(err error) {
+		fmt.Fprintf(stderr, "woodcutter: %v\n", err)
+	}
+
+	var w io.Writer = l
+	if tee {
+		w = io.MultiWriter(l, stdout)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(w, stdin)
+		done <- err
+	}()
+
+	code := 0
+	for stop := false; !stop; {
+		select {
+		case err := <-done:
+			if err != nil {
+				fmt.Fprintf(stderr, "woodcutter: %v\n", err)
+				code = 1
+			}
+			stop = true
+		case sig := <-sigs:
+			if sig == syscall.SIGHUP {
+				if err := l.Rotate(); err != nil {
+					fmt.Fprintf(stderr, "woodcutter: %v\n", err)
+				}
+				continue
+			}
+			stop = true
+		}
+	}
+
+	if err := l.Close(); err != nil {
+		fmt.Fprintf(stderr, "woodcutter: %v\n", err)
+		code = 1
+	}
+	return code
+}
+
+// newLogger returns the Logger configured by the command line arguments, and
+// whether the input should also be copied to stdout.
+func newLogger(args []string, output io.Writer) (*woodcutter.Logger, bool, error) {
+	l := &woodcutter.Logger{WholeRecords: true}
+
+	fs := flag.NewFlagSet("woodcutter", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: woodcutter [flags] filename\n\nflags:\n")
+		fs.PrintDefaults()
+	}
+	fs.IntVar(&l.MaxSize, "max-size", 0, "rotate when the log file reaches this many megabytes (default 100)")
+	fs.IntVar(&l.MaxBackups, "max-backups", 0, "number of backups to keep, 0 for all")
+	fs.IntVar(&l.MaxAge, "max-age", 0, "days to keep backups for, 0 for ever")
+	fs.IntVar(&l.MaxTotalSize, "max-total-size", 0, "megabytes of backups to keep, 0 for no limit")
+	fs.StringVar(&l.Schedule, "schedule", "", "also rotate on a schedule: hourly, daily, weekly, an interval like 6h or a cron expression")
+	fs.BoolVar(&l.Compress, "compress", false, "compress backups")
+	fs.StringVar(&l.Compression, "compression", "", "compression of backups: gzip, zstd or zlib (default gzip)")
+	fs.BoolVar(&l.LocalTime, "local-time", false, "use local time in backup names instead of UTC")
+	fs.BoolVar(&l.NumberedBackups, "numbered", false, "name backups with sequence numbers like logrotate")
+	fs.StringVar(&l.BackupTemplate, "backup-template", "", "template of backup names")
+	fs.StringVar(&l.ArchiveDir, "archive-dir", "", "directory to move backups to")
+	fs.BoolVar(&l.SymlinkCurrent, "symlink", false, "make filename a link to the current file")
+	fs.DurationVar(&l.FlushInterval, "flush-interval", 0, "how often buffered data is written out (default 1s)")
+	fs.IntVar(&l.BufferSize, "buffer-size", 0, "bytes to buffer before writing to the log file, 0 for none")
+	tee := fs.Bool("tee", false, "also copy the input to stdout")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return nil, false, errors.New("expected one filename")
+	}
+	l.Filename = fs.Arg(0)
+	return l, *tee, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_Pipe(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "foobar.log")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-tee", filename}, strings.NewReader("one\ntwo"), &stdout, &stderr, nil)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "", stderr.String())
+	assert.Equal(t, "one\ntwo", stdout.String())
+
+	b, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, "one\ntwo", string(b))
+}
+
+func TestRun_Signals(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "foobar.log")
+
+	stdin, w := io.Pipe()
+	defer w.Close()
+	sigs := make(chan os.Signal)
+	done := make(chan int)
+	go func() {
+		done <- run([]string{filename}, stdin, io.Discard, io.Discard, sigs)
+	}()
+
+	_, err := w.Write([]byte("one\n"))
+	assert.Nil(t, err)
+	// give the line time to get to the log file.
+	<-time.After(100 * time.Millisecond)
+
+	sigs <- syscall.SIGHUP
+	<-time.After(100 * time.Millisecond)
+	_, err = w.Write([]byte("two\n"))
+	assert.Nil(t, err)
+	<-time.After(100 * time.Millisecond)
+
+	sigs <- syscall.SIGTERM
+	select {
+	case code := <-done:
+		assert.Equal(t, 0, code)
+	case <-time.After(time.Second):
+		t.Fatal("run didn't return after SIGTERM")
+	}
+
+	files, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(files))
+	b, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, "two\n", string(b))
+}
+
+func TestNewLogger_Flags(t *testing.T) {
+	l, tee, err := newLogger([]string{
+		"-max-size", "10", "-max-backups", "3", "-compress", "-compression", "zstd",
+		"-schedule", "daily", "foo.log",
+	}, io.Discard)
+	assert.Nil(t, err)
+	assert.False(t, tee)
+	assert.Equal(t, "foo.log", l.Filename)
+	assert.Equal(t, 10, l.MaxSize)
+	assert.Equal(t, 3, l.MaxBackups)
+	assert.True(t, l.Compress)
+	assert.Equal(t, "zstd", l.Compression)
+	assert.Equal(t, "daily", l.Schedule)
+	assert.True(t, l.WholeRecords)
+
+	_, _, err = newLogger([]string{"-compress"}, io.Discard)
+	assert.NotNil(t, err)
+}
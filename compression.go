@@ -0,0 +1,118 @@
+package woodcutter
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Values of Logger.Compression.
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+	CompressionZlib = "zlib"
+)
+
+const (
+	zstdSuffix = ".zst"
+	zlibSuffix = ".zz"
+)
+
+// compressSuffixes lists the suffixes of every supported compression, so that
+// backups are recognized whichever compression they were made with.
+//
+//nolint:gochecknoglobals // read-only table of suffixes
+var compressSuffixes = []string{compressSuffix, zstdSuffix, zlibSuffix}
+
+// compressor compresses backups with one of the supported algorithms.
+type compressor struct {
+	suffix    string
+	newWriter func(w io.Writer) (io.WriteCloser, error)
+}
+
+// compressor returns the compressor selected by Compression and
+// CompressionLevel.
+func (l *Logger) compressor() (compressor, error) {
+	level := l.CompressionLevel
+	switch l.Compression {
+	case "", CompressionGzip, CompressionZlib:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		} else if level < gzip.BestSpeed || level > gzip.BestCompression {
+			return compressor{}, fmt.Errorf("invalid %s compression level %d", l.compression(), level)
+		}
+		if l.Compression == CompressionZlib {
+			return compressor{
+				suffix: zlibSuffix,
+				newWriter: func(w io.Writer) (io.WriteCloser, error) {
+					return zlib.NewWriterLevel(w, level)
+				},
+			}, nil
+		}
+		return compressor{
+			suffix: compressSuffix,
+			newWriter: func(w io.Writer) (io.WriteCloser, error) {
+				return gzip.NewWriterLevel(w, level)
+			},
+		}, nil
+	case CompressionZstd:
+		const maxZstdLevel = 22
+		encoderLevel := zstd.SpeedDefault
+		if level != 0 {
+			if level < 1 || level > maxZstdLevel {
+				return compressor{}, fmt.Errorf("invalid zstd compression level %d", level)
+			}
+			encoderLevel = zstd.EncoderLevelFromZstd(level)
+		}
+		return compressor{
+			suffix: zstdSuffix,
+			newWriter: func(w io.Writer) (io.WriteCloser, error) {
+				return zstd.NewWriter(w, zstd.WithEncoderLevel(encoderLevel))
+			},
+		}, nil
+	default:
+		return compressor{}, fmt.Errorf("unknown compression %q", l.Compression)
+	}
+}
+
+// compression returns the name of the selected compression.
+func (l *Logger) compression() string {
+	if l.Compression == "" {
+		return CompressionGzip
+	}
+	return l.Compression
+}
+
+// compressionSuffix returns the compression suffix of filename, or an empty
+// string if it is not compressed.
+func compressionSuffix(filename string) string {
+	for _, suffix := range compressSuffixes {
+		if strings.HasSuffix(filename, suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// newDecompressor returns a reader of the decompressed contents of r, which
+// was compressed with the compression using suffix.
+func newDecompressor(suffix string, r io.Reader) (io.ReadCloser, error) {
+	switch suffix {
+	case compressSuffix:
+		return gzip.NewReader(r)
+	case zstdSuffix:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	case zlibSuffix:
+		return zlib.NewReader(r)
+	default:
+		return nil, fmt.Errorf("unknown compression suffix %q", suffix)
+	}
+}
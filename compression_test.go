@@ -0,0 +1,116 @@
+package woodcutter
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompression_Algorithms(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+
+	tests := []struct {
+		compression string
+		level       int
+		suffix      string
+		newReader   func(r io.Reader) (io.Reader, error)
+	}{
+		{"", 0, ".gz", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{CompressionGzip, 9, ".gz", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{CompressionZlib, 1, ".zz", func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }},
+		{CompressionZstd, 0, ".zst", func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) }},
+		{CompressionZstd, 19, ".zst", func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) }},
+	}
+
+	for _, test := range tests {
+		dir := t.TempDir()
+		l := &Logger{
+			Filename:         logFile(dir),
+			Compress:         true,
+			Compression:      test.compression,
+			CompressionLevel: test.level,
+		}
+
+		b := []byte("boo!")
+		n, err := l.Write(b)
+		assert.Nil(t, err, test.compression)
+		assert.Equal(t, len(b), n, test.compression)
+
+		newFakeTime()
+		err = l.Rotate()
+		assert.Nil(t, err, test.compression)
+
+		// we need to wait a little bit since the files get compressed on a
+		// different goroutine.
+		<-time.After(300 * time.Millisecond)
+		assert.Nil(t, l.Close())
+
+		compressed := backupFile(dir) + test.suffix
+		f, err := os.Open(compressed)
+		assert.Nil(t, err, test.compression)
+		r, err := test.newReader(f)
+		assert.Nil(t, err, test.compression)
+		data, err := io.ReadAll(r)
+		assert.Nil(t, err, test.compression)
+		assert.Equal(t, b, data, test.compression)
+		f.Close()
+
+		assert.NoFileExists(t, backupFile(dir))
+		fileCount(t, dir, 2)
+	}
+}
+
+func TestCompression_MixedSuffixes(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	// backups compressed with every algorithm all count as backups.
+	var backups []string
+	for _, suffix := range []string{".zz", ".gz", ".zst"} {
+		newFakeTime()
+		backup := backupFile(dir) + suffix
+		err := os.WriteFile(backup, []byte("data"), 0o644)
+		assert.Nil(t, err)
+		backups = append(backups, backup)
+	}
+
+	l := &Logger{
+		Filename:    logFile(dir),
+		MaxBackups:  1,
+		Compression: CompressionZstd,
+	}
+	err := l.millRunOnce()
+	assert.Nil(t, err)
+
+	assert.NoFileExists(t, backups[0])
+	assert.NoFileExists(t, backups[1])
+	assert.FileExists(t, backups[2])
+}
+
+func TestCompression_Invalid(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+
+	for _, l := range []*Logger{
+		{Compression: "xz"},
+		{Compression: CompressionGzip, CompressionLevel: 10},
+		{Compression: CompressionZlib, CompressionLevel: -1},
+		{Compression: CompressionZstd, CompressionLevel: 23},
+	} {
+		dir := t.TempDir()
+		l.Filename = logFile(dir)
+		n, err := l.Write([]byte("boo!"))
+		assert.NotNil(t, err, l.Compression)
+		assert.Equal(t, 0, n)
+		assert.NoFileExists(t, logFile(dir))
+		l.Close()
+	}
+}
@@ -0,0 +1,68 @@
+package woodcutter
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// copyTruncate copies the log file to backup and then empties it in place,
+// for CopyTruncate, so that the log file keeps its inode.  If durable is set,
+// the copy is synced to disk before the log file is truncated.
+func copyTruncate(name, backup string, info os.FileInfo, durable bool) error {
+	if err := copyFile(name, backup, info, durable); err != nil {
+		return err
+	}
+	return os.Truncate(name, 0)
+}
+
+// copyFile copies src, described by info, to dst with the same mode and
+// owner.  If durable is set, dst is synced to disk.
+func copyFile(src, dst string, info os.FileInfo, durable bool) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("can't open log file: %w", err)
+	}
+	defer in.Close()
+
+	// this is a no-op anywhere but linux
+	if err = chown(dst, info); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return fmt.Errorf("can't open backup file: %w", err)
+	}
+	defer out.Close()
+
+	defer func() {
+		if err != nil {
+			os.Remove(dst)
+			err = fmt.Errorf("can't copy log file: %w", err)
+		}
+	}()
+
+	if _, err = io.Copy(out, in); err != nil {
+		return err
+	}
+	if durable {
+		if err = fileSync(out); err != nil {
+			return err
+		}
+	}
+	return out.Close()
+}
+
+// refreshSize updates the size of the current file from disk, since others
+// may write to it.
+func (l *Logger) refreshSize() error {
+	info, err := l.file.Stat()
+	if err != nil {
+		return fmt.Errorf("can't get log file info: %w", err)
+	}
+	l.size = info.Size()
+	if l.buf != nil {
+		l.size += int64(l.buf.Buffered())
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package woodcutter
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyTruncate_KeepsInode(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:     filename,
+		CopyTruncate: true,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+	before, err := os.Stat(filename)
+	assert.Nil(t, err)
+
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	fileContainsContent(t, backupFile(dir), b)
+	after, err := os.Stat(filename)
+	assert.Nil(t, err)
+	assert.True(t, os.SameFile(before, after))
+	assert.Equal(t, int64(0), after.Size())
+
+	b2 := []byte("foo!")
+	n, err = l.Write(b2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b2), n)
+	existsWithSize(t, filename, int64(len(b2)))
+	fileContainsContent(t, filename, b2)
+	fileCount(t, dir, 2)
+}
+
+func TestCopyTruncate_SharedFile(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:     filename,
+		MaxSize:      10,
+		CopyTruncate: true,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	// another process writes to the file through a descriptor it inherited.
+	other, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0o644)
+	assert.Nil(t, err)
+	defer other.Close()
+	_, err = other.Write([]byte("ext!"))
+	assert.Nil(t, err)
+
+	// the bytes written by the other process count towards MaxSize.
+	newFakeTime()
+	b2 := []byte("foo!")
+	n, err = l.Write(b2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b2), n)
+	fileContainsContent(t, backupFile(dir), []byte("boo!ext!"))
+
+	_, err = other.Write([]byte("more"))
+	assert.Nil(t, err)
+	existsWithSize(t, filename, 8)
+	fileContainsContent(t, filename, []byte("foo!more"))
+}
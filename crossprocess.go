@@ -0,0 +1,60 @@
+package woodcutter
+
+import (
+	"fmt"
+	"os"
+)
+
+// lockSuffix is appended to the log file name to make the name of the lock
+// file used when CrossProcess is set.
+const lockSuffix = ".lock"
+
+// lockRotation takes the lock that coordinates rotation and housekeeping with
+// other processes, and returns the function that releases it.  It does nothing
+// unless CrossProcess is set.  The lock is also exclusive between goroutines
+// of this process, so it must not be taken twice by the same goroutine, and is
+// always taken before l.millMu.
+func (l *Logger) lockRotation() (func(), error) {
+	if !l.CrossProcess {
+		return func() {}, nil
+	}
+
+	if err := os.MkdirAll(l.dir(), 0o755); err != nil {
+		return nil, fmt.Errorf("can't make directories for lock file: %w", err)
+	}
+	f, err := os.OpenFile(l.filename()+lockSuffix, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("can't open lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("can't lock lock file: %w", err)
+	}
+	return func() {
+		_ = unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// rotateDue rotates the log file before writing p.  When CrossProcess is set,
+// it first checks, holding the lock, that another process hasn't already
+// rotated it.
+func (l *Logger) rotateDue(p []byte) error {
+	if !l.CrossProcess {
+		return l.rotate()
+	}
+
+	unlock, err := l.lockRotation()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := l.reopenIfMoved(); err != nil {
+		return err
+	}
+	if !l.shouldRotate(l.size, p) && !l.scheduleDue() {
+		return nil
+	}
+	return l.rotateFile()
+}
@@ -0,0 +1,76 @@
+package woodcutter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrossProcess_SharedRotation(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	dir := t.TempDir()
+
+	// two Loggers with the same configuration stand in for two processes.
+	filename := logFile(dir)
+	a := &Logger{Filename: filename, MaxSize: 10, CrossProcess: true}
+	defer a.Close()
+	b := &Logger{Filename: filename, MaxSize: 10, CrossProcess: true}
+	defer b.Close()
+
+	_, err := a.Write([]byte("aaaa"))
+	assert.Nil(t, err)
+	_, err = b.Write([]byte("bbbb"))
+	assert.Nil(t, err)
+	fileContainsContent(t, filename, []byte("aaaabbbb"))
+
+	// a sees the bytes written by b, so it rotates.
+	newFakeTime()
+	_, err = a.Write([]byte("cccc"))
+	assert.Nil(t, err)
+	fileContainsContent(t, backupFile(dir), []byte("aaaabbbb"))
+	existsWithSize(t, filename, 4)
+
+	// b follows the rotation instead of rotating again.
+	_, err = b.Write([]byte("dddd"))
+	assert.Nil(t, err)
+	fileContainsContent(t, filename, []byte("ccccdddd"))
+
+	// the log file, the backup and the lock file.
+	fileCount(t, dir, 3)
+	assert.FileExists(t, filename+lockSuffix)
+}
+
+func TestCrossProcess_Lock(t *testing.T) {
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	a := &Logger{Filename: filename, CrossProcess: true}
+	b := &Logger{Filename: filename, CrossProcess: true}
+
+	unlock, err := a.lockRotation()
+	assert.Nil(t, err)
+
+	locked := make(chan struct{})
+	go func() {
+		defer close(locked)
+		unlockB, err := b.lockRotation()
+		assert.Nil(t, err)
+		unlockB()
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("lock was taken twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("lock was not released")
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package woodcutter
+
+import (
+	"errors"
+	"os"
+)
+
+func diskFree(_ string) (int64, error) {
+	return 0, errors.New("free disk space is not supported on this platform")
+}
+
+func sameDevice(_, _ os.FileInfo) bool {
+	return true
+}
@@ -0,0 +1,27 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package woodcutter
+
+import (
+	"os"
+	"syscall"
+)
+
+// diskFree returns the number of bytes available to unprivileged users on the
+// filesystem holding dir.
+func diskFree(dir string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil //nolint:unconvert // field types vary by platform
+}
+
+// sameDevice reports whether the files described by a and b are on the same
+// filesystem.
+func sameDevice(a, b os.FileInfo) bool {
+	sa, okA := a.Sys().(*syscall.Stat_t)
+	sb, okB := b.Sys().(*syscall.Stat_t)
+	return !okA || !okB || sa.Dev == sb.Dev
+}
@@ -0,0 +1,20 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package woodcutter
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskFree_TempDir(t *testing.T) {
+	free, err := diskFree(t.TempDir())
+	assert.Nil(t, err)
+	assert.Greater(t, free, int64(0))
+
+	_, err = diskFree(filepath.Join(t.TempDir(), "missing"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,126 @@
+package woodcutter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Values of Logger.DiskFullMode.
+const (
+	DiskFullDrop   = "drop"
+	DiskFullError  = "error"
+	DiskFullStderr = "stderr"
+)
+
+// ErrDiskFull is returned by Write when the Logger is short of free disk space
+// and DiskFullMode is "error".
+var ErrDiskFull = errors.New("not enough free disk space for log file")
+
+// checkDiskFullMode returns an error if DiskFullMode is not a known mode.
+func (l *Logger) checkDiskFullMode() error {
+	switch l.DiskFullMode {
+	case "", DiskFullDrop, DiskFullError, DiskFullStderr:
+		return nil
+	default:
+		return fmt.Errorf("unknown disk full mode %q", l.DiskFullMode)
+	}
+}
+
+// hasFreeSpace reports whether the filesystem holding the log files has at
+// least MinFreeSpace megabytes available.  If free space can't be determined,
+// it assumes there is enough.
+func (l *Logger) hasFreeSpace() bool {
+	if l.MinFreeSpace <= 0 {
+		return true
+	}
+	free, err := diskFreeSpace(l.dir())
+	if err != nil {
+		return true
+	}
+	return free >= int64(l.MinFreeSpace)*int64(megabyte)
+}
+
+// ensureFreeSpace removes backups, oldest first, until there is MinFreeSpace
+// available, and records whether Write must switch to DiskFullMode because
+// there still isn't.  Backups on another filesystem than the log file, in
+// ArchiveDir, are left alone since removing them frees nothing.  The caller
+// must hold l.millMu.
+func (l *Logger) ensureFreeSpace() error {
+	if l.MinFreeSpace <= 0 {
+		return nil
+	}
+
+	var err error
+	if !l.hasFreeSpace() {
+		var files []logInfo
+		files, err = l.oldLogFiles()
+		logDir, statErr := os.Stat(l.dir())
+		for i := len(files) - 1; i >= 0 && !l.hasFreeSpace(); i-- {
+			if files[i].dir != l.dir() && (statErr != nil || !l.onFilesystem(files[i], logDir)) {
+				continue
+			}
+			errRemove := l.removeBackup(files[i])
+			if err == nil && errRemove != nil {
+				err = errRemove
+			}
+		}
+	}
+
+	l.setDiskFull(!l.hasFreeSpace())
+	return err
+}
+
+// onFilesystem reports whether the backup f is on the filesystem of the
+// directory described by dir.
+func (l *Logger) onFilesystem(f logInfo, dir os.FileInfo) bool {
+	info, err := os.Stat(f.path())
+	return err == nil && sameFilesystem(info, dir)
+}
+
+// setDiskFull records whether the disk is full, sending an event when that
+// changes.
+func (l *Logger) setDiskFull(full bool) {
+	if l.diskFull.Swap(full) == full {
+		return
+	}
+	kind := EventDiskRecovered
+	if full {
+		kind = EventDiskFull
+	}
+	l.notify(Event{Kind: kind, Filename: l.filename()})
+}
+
+// checkDiskFull reports whether writes must be handled according to
+// DiskFullMode.  While the disk is full, free space is checked again at most
+// every diskCheckInterval so that writing resumes once space is available.
+func (l *Logger) checkDiskFull() bool {
+	if !l.diskFull.Load() {
+		return false
+	}
+	now := currentTime()
+	if now.Sub(l.lastDiskCheck) < diskCheckInterval {
+		return true
+	}
+	l.lastDiskCheck = now
+	if l.hasFreeSpace() {
+		l.setDiskFull(false)
+		return false
+	}
+	// let the mill try to make room again.
+	l.mill()
+	return true
+}
+
+// writeDiskFull handles a write while the disk is full, according to
+// DiskFullMode.
+func (l *Logger) writeDiskFull(p []byte) (int, error) {
+	switch l.DiskFullMode {
+	case DiskFullError:
+		return 0, ErrDiskFull
+	case DiskFullStderr:
+		return os.Stderr.Write(p)
+	default:
+		return len(p), nil
+	}
+}
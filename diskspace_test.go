@@ -0,0 +1,178 @@
+package woodcutter
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskSpace_PruneBackups(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	defer resetMocks()
+	dir := t.TempDir()
+
+	// pretend every file in the directory takes up 10 bytes of a 40 byte disk.
+	diskFreeSpace = func(string) (int64, error) {
+		files, err := os.ReadDir(dir)
+		return int64(40 - 10*len(files)), err
+	}
+
+	data := []byte("data")
+	oldest := backupFile(dir)
+	err := os.WriteFile(oldest, data, 0o644)
+	assert.Nil(t, err)
+	newFakeTime()
+	older := backupFile(dir)
+	err = os.WriteFile(older, data, 0o644)
+	assert.Nil(t, err)
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:     filename,
+		MinFreeSpace: 15,
+		DiskFullMode: DiskFullError,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	// 4 files leave 0 bytes, so the two oldest backups are removed to get
+	// back to 20 bytes.
+	assert.NoFileExists(t, oldest)
+	assert.NoFileExists(t, older)
+	fileContainsContent(t, backupFile(dir), b)
+	fileCount(t, dir, 2)
+
+	// there was enough space in the end.
+	n, err = l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+}
+
+func TestDiskSpace_SkipOtherFilesystem(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	defer resetMocks()
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive")
+	err := os.Mkdir(archive, 0o755)
+	assert.Nil(t, err)
+
+	// the log directory is always full, and the archive is elsewhere.
+	diskFreeSpace = func(string) (int64, error) {
+		return 0, nil
+	}
+	sameFilesystem = func(_, _ os.FileInfo) bool {
+		return false
+	}
+
+	archived := backupFile(archive)
+	err = os.WriteFile(archived, []byte("data"), 0o644)
+	assert.Nil(t, err)
+	newFakeTime()
+
+	l := &Logger{
+		Filename:     logFile(dir),
+		ArchiveDir:   archive,
+		MinFreeSpace: 1,
+	}
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	assert.Nil(t, err)
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	// only the backup next to the log file could free space.
+	assert.FileExists(t, archived)
+	assert.NoFileExists(t, backupFile(dir))
+}
+
+func TestDiskSpace_DiskFullModes(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	diskCheckInterval = time.Hour
+	defer resetMocks()
+
+	// the mill reads free space in the background.
+	var free atomic.Int64
+	diskFreeSpace = func(string) (int64, error) {
+		return free.Load(), nil
+	}
+
+	for _, mode := range []string{"", DiskFullDrop, DiskFullError, DiskFullStderr} {
+		dir := t.TempDir()
+		filename := logFile(dir)
+		l := &Logger{
+			Filename:     filename,
+			MinFreeSpace: 10,
+			DiskFullMode: mode,
+		}
+
+		free.Store(100)
+		b := []byte("boo!")
+		n, err := l.Write(b)
+		assert.Nil(t, err, mode)
+		assert.Equal(t, len(b), n, mode)
+
+		// nothing can be pruned.
+		free.Store(0)
+		newFakeTime()
+		err = l.Rotate()
+		assert.Nil(t, err, mode)
+
+		b2 := []byte("foo!")
+		n, err = l.Write(b2)
+		if mode == DiskFullError {
+			assert.ErrorIs(t, err, ErrDiskFull)
+			assert.Equal(t, 0, n)
+		} else {
+			assert.Nil(t, err, mode)
+			assert.Equal(t, len(b2), n, mode)
+		}
+		content, err := os.ReadFile(filename)
+		assert.Nil(t, err, mode)
+		assert.Empty(t, content, mode)
+
+		// writing resumes once space is available again.
+		free.Store(100)
+		setFakeTime(fakeTime().Add(diskCheckInterval))
+		b3 := []byte("baz!")
+		n, err = l.Write(b3)
+		assert.Nil(t, err, mode)
+		assert.Equal(t, len(b3), n, mode)
+		fileContainsContent(t, filename, b3)
+
+		assert.Nil(t, l.Close())
+	}
+}
+
+func TestDiskSpace_InvalidMode(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+	l := &Logger{
+		Filename:     logFile(dir),
+		DiskFullMode: "panic",
+	}
+	defer l.Close()
+	n, err := l.Write([]byte("boo!"))
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, n)
+}
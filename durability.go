@@ -0,0 +1,86 @@
+package woodcutter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Values of Logger.Durability.
+const (
+	DurabilityNever    = "never"
+	DurabilityWrite    = "write"
+	DurabilityBytes    = "bytes"
+	DurabilityInterval = "interval"
+	DurabilityRotate   = "rotate"
+)
+
+// checkDurability returns an error if Durability is not a known policy, or
+// lacks the setting it depends on.
+func (l *Logger) checkDurability() error {
+	switch l.Durability {
+	case "", DurabilityNever, DurabilityWrite, DurabilityRotate:
+		return nil
+	case DurabilityBytes:
+		if l.SyncBytes <= 0 {
+			return errors.New("durability \"bytes\" requires SyncBytes")
+		}
+		return nil
+	case DurabilityInterval:
+		if l.SyncInterval <= 0 {
+			return errors.New("durability \"interval\" requires SyncInterval")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown durability %q", l.Durability)
+	}
+}
+
+// durable reports whether the log files are synced to disk at all.
+func (l *Logger) durable() bool {
+	return l.Durability != "" && l.Durability != DurabilityNever
+}
+
+// sync flushes buffered data and commits the current file to disk.
+func (l *Logger) sync() error {
+	if l.file == nil {
+		return nil
+	}
+	if err := l.flush(); err != nil {
+		return err
+	}
+	l.unsynced = 0
+	if err := fileSync(l.file); err != nil {
+		return fmt.Errorf("can't sync log file: %w", err)
+	}
+	return nil
+}
+
+// syncAfterWrite syncs the current file after n bytes were written to it, if
+// the durability policy asks for it.
+func (l *Logger) syncAfterWrite(n int) error {
+	switch l.Durability {
+	case DurabilityWrite:
+		return l.sync()
+	case DurabilityBytes, DurabilityInterval:
+		l.unsynced += int64(n)
+		if l.Durability == DurabilityBytes && l.unsynced >= int64(l.SyncBytes) {
+			return l.sync()
+		}
+	}
+	return nil
+}
+
+// syncDue reports whether the background goroutine should sync the current
+// file.
+func (l *Logger) syncDue() bool {
+	return l.Durability == DurabilityInterval && l.file != nil && l.unsynced > 0
+}
+
+// syncLogDir commits renames and new files in dir, the log or archive
+// directory, to disk, if the log files are synced at all.
+func (l *Logger) syncLogDir(dir string) error {
+	if !l.durable() {
+		return nil
+	}
+	return dirSync(dir)
+}
@@ -0,0 +1,174 @@
+package woodcutter
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countSyncs mocks out fileSync and dirSync to count the calls made to them.
+func countSyncs() (files, dirs *atomic.Int32) {
+	files, dirs = new(atomic.Int32), new(atomic.Int32)
+	fileSync = func(*os.File) error {
+		files.Add(1)
+		return nil
+	}
+	dirSync = func(string) error {
+		dirs.Add(1)
+		return nil
+	}
+	return files, dirs
+}
+
+func TestDurability_Write(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	files, _ := countSyncs()
+	defer resetMocks()
+	dir := t.TempDir()
+
+	l := &Logger{
+		Filename:   logFile(dir),
+		Durability: DurabilityWrite,
+	}
+	defer l.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := l.Write([]byte("boo!"))
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, int32(3), files.Load())
+}
+
+func TestDurability_Bytes(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	files, _ := countSyncs()
+	defer resetMocks()
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:   filename,
+		Durability: DurabilityBytes,
+		SyncBytes:  10,
+		BufferSize: 1024,
+	}
+	defer l.Close()
+
+	// syncs once the third write brings the unsynced bytes to 12.
+	for i := 0; i < 5; i++ {
+		_, err := l.Write([]byte("boo!"))
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, int32(1), files.Load())
+
+	// syncing flushes the buffer first.
+	fileContainsContent(t, filename, []byte("boo!boo!boo!"))
+}
+
+func TestDurability_Interval(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	files, _ := countSyncs()
+	defer resetMocks()
+	dir := t.TempDir()
+
+	l := &Logger{
+		Filename:     logFile(dir),
+		Durability:   DurabilityInterval,
+		SyncInterval: 10 * time.Millisecond,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(0), files.Load())
+
+	// we need to wait a little bit since the sync happens on a different
+	// goroutine.
+	<-time.After(100 * time.Millisecond)
+
+	// nothing was written since, so there is nothing more to sync.
+	assert.Equal(t, int32(1), files.Load())
+}
+
+func TestDurability_Rotate(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	files, dirs := countSyncs()
+	defer resetMocks()
+	dir := t.TempDir()
+
+	l := &Logger{
+		Filename:   logFile(dir),
+		Durability: DurabilityRotate,
+		Compress:   true,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(0), files.Load())
+	assert.Equal(t, int32(1), dirs.Load())
+
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	// we need to wait a little bit since the files get compressed on a
+	// different goroutine.
+	<-time.After(300 * time.Millisecond)
+
+	// the rotated file and the compressed backup, and the directory after
+	// the rename and the compression.
+	assert.Equal(t, int32(2), files.Load())
+	assert.Equal(t, int32(3), dirs.Load())
+	assert.FileExists(t, backupFile(dir)+compressSuffix)
+}
+
+func TestDurability_Never(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	files, dirs := countSyncs()
+	defer resetMocks()
+	dir := t.TempDir()
+
+	l := &Logger{
+		Filename: logFile(dir),
+		Compress: true,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	assert.Nil(t, err)
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+	<-time.After(300 * time.Millisecond)
+
+	assert.Equal(t, int32(0), files.Load())
+	assert.Equal(t, int32(0), dirs.Load())
+}
+
+func TestDurability_Invalid(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+
+	for _, l := range []*Logger{
+		{Durability: "sometimes"},
+		{Durability: DurabilityBytes},
+		{Durability: DurabilityInterval},
+	} {
+		dir := t.TempDir()
+		l.Filename = logFile(dir)
+		n, err := l.Write([]byte("boo!"))
+		assert.NotNil(t, err, l.Durability)
+		assert.Equal(t, 0, n)
+		assert.NoFileExists(t, logFile(dir))
+		l.Close()
+	}
+}
@@ -0,0 +1,200 @@
+package woodcutter
+
+import (
+	"os"
+	"time"
+)
+
+// EventKind identifies what happened in an Event.
+type EventKind int
+
+const (
+	// EventRotationStarted is sent before the log file is moved aside.
+	EventRotationStarted EventKind = iota + 1
+
+	// EventRotationFinished is sent once the log file has been moved aside
+	// and a new one created.
+	EventRotationFinished
+
+	// EventBackupCompressed is sent once a backup has been compressed.
+	EventBackupCompressed
+
+	// EventBackupRemoved is sent once a backup has been deleted.
+	EventBackupRemoved
+
+	// EventDiskFull is sent when writes start being handled according to
+	// DiskFullMode.
+	EventDiskFull
+
+	// EventDiskRecovered is sent when writes to the log file resume.
+	EventDiskRecovered
+
+	// EventOversizeWrite is sent when a write larger than MaxSize was handled
+	// according to OversizeMode.
+	EventOversizeWrite
+
+	// EventReopened is sent when the log file was reopened after it had been
+	// moved or deleted by someone else.
+	EventReopened
+
+	// EventBackupArchived is sent once a backup has been moved to ArchiveDir
+	// or to its BackupDirLayout subdirectory.
+	EventBackupArchived
+)
+
+// String returns a readable name for the kind of event.
+func (k EventKind) String() string {
+	switch k {
+	case EventRotationStarted:
+		return "rotation started"
+	case EventRotationFinished:
+		return "rotation finished"
+	case EventBackupCompressed:
+		return "backup compressed"
+	case EventBackupRemoved:
+		return "backup removed"
+	case EventDiskFull:
+		return "disk full"
+	case EventDiskRecovered:
+		return "disk recovered"
+	case EventOversizeWrite:
+		return "oversize write"
+	case EventReopened:
+		return "reopened"
+	case EventBackupArchived:
+		return "backup archived"
+	default:
+		return "unknown event"
+	}
+}
+
+// Event describes something the Logger did to its files, for monitoring.
+type Event struct {
+	// Kind is what happened.
+	Kind EventKind
+
+	// Time is when it happened.
+	Time time.Time
+
+	// Filename is the file the event is about: the log file for rotations
+	// and disk events, or the backup that was compressed or removed.
+	Filename string
+
+	// Backup is the file that resulted from the operation: the name the log
+	// file was moved to for EventRotationFinished, the compressed file for
+	// EventBackupCompressed, or the archived file for EventBackupArchived.
+	Backup string
+
+	// Size is the size in bytes of Filename, of Backup for
+	// EventBackupCompressed, or of the write for EventOversizeWrite.
+	Size int64
+
+	// Duration is how long the operation took, for EventRotationFinished and
+	// EventBackupCompressed.
+	Duration time.Duration
+
+	// Mode is the OversizeMode that was applied, for EventOversizeWrite.
+	Mode string
+
+	// backupInfo identifies the file Backup was right after the rotation, for
+	// EventRotationFinished, so that Follower can recognize it once it has
+	// been compressed or moved.
+	backupInfo os.FileInfo
+}
+
+// notification is an event or an error waiting to be delivered.
+type notification struct {
+	event Event
+	err   error
+}
+
+// notify queues an event for OnEvent.  Events are queued rather than
+// delivered right away since they mostly happen while the Logger is locked,
+// and handlers are free to write to the Logger.
+func (l *Logger) notify(e Event) {
+	if l.OnEvent == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = currentTime()
+	}
+	l.eventMu.Lock()
+	l.pending = append(l.pending, notification{event: e})
+	l.eventMu.Unlock()
+}
+
+// report queues an error for OnError.
+func (l *Logger) report(err error) {
+	if l.OnError == nil || err == nil {
+		return
+	}
+	l.eventMu.Lock()
+	l.pending = append(l.pending, notification{err: err})
+	l.eventMu.Unlock()
+}
+
+// deliver calls OnEvent and OnError with the queued events and errors.  It
+// must be called without holding l.mu or l.millMu.
+func (l *Logger) deliver() {
+	l.eventMu.Lock()
+	pending := l.pending
+	l.pending = nil
+	l.eventMu.Unlock()
+
+	for _, n := range pending {
+		if n.err != nil {
+			if l.OnError != nil {
+				l.OnError(n.err)
+			}
+		} else if l.OnEvent != nil {
+			l.OnEvent(n.event)
+		}
+	}
+}
+
+// removeBackup deletes the given backup and sends EventBackupRemoved.
+func (l *Logger) removeBackup(f logInfo) error {
+	name := f.path()
+	var size int64
+	if info, err := f.Info(); err == nil {
+		size = info.Size()
+	}
+	if err := os.Remove(name); err != nil {
+		return err
+	}
+	if err := removeIndex(f); err != nil {
+		return err
+	}
+	l.notePrunable(f.dir)
+	l.notify(Event{Kind: EventBackupRemoved, Filename: name, Size: size})
+	return nil
+}
+
+// compressBackup compresses the given backup and sends EventBackupCompressed.
+func (l *Logger) compressBackup(f logInfo) error {
+	c, err := l.compressor()
+	if err != nil {
+		return err
+	}
+	src := f.path()
+	dst := src + c.suffix
+	start := time.Now()
+	if err := compressLogFile(src, dst, c, l.durable()); err != nil {
+		return err
+	}
+	if err := l.syncLogDir(f.dir); err != nil {
+		return err
+	}
+	var size int64
+	if info, err := osStat(dst); err == nil {
+		size = info.Size()
+	}
+	l.notify(Event{
+		Kind:     EventBackupCompressed,
+		Filename: src,
+		Backup:   dst,
+		Size:     size,
+		Duration: time.Since(start),
+	})
+	return nil
+}
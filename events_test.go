@@ -0,0 +1,246 @@
+package woodcutter
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// eventRecorder collects the events and errors reported by a Logger.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+	errs   []error
+}
+
+func (r *eventRecorder) onEvent(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) onError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, err)
+}
+
+// kinds returns the kinds of the recorded events, in order.
+func (r *eventRecorder) kinds() []EventKind {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var kinds []EventKind
+	for _, e := range r.events {
+		kinds = append(kinds, e.Kind)
+	}
+	return kinds
+}
+
+// find returns the first recorded event of the given kind.
+func (r *eventRecorder) find(kind EventKind) (Event, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.events {
+		if e.Kind == kind {
+			return e, true
+		}
+	}
+	return Event{}, false
+}
+
+func TestEvents_Rotation(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	dir := t.TempDir()
+
+	rec := &eventRecorder{}
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+		MaxSize:  10,
+		OnEvent:  rec.onEvent,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+	assert.Empty(t, rec.kinds())
+
+	newFakeTime()
+	b2 := []byte("foooooo!")
+	n, err = l.Write(b2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b2), n)
+
+	assert.Equal(t, []EventKind{EventRotationStarted, EventRotationFinished}, rec.kinds())
+	finished, ok := rec.find(EventRotationFinished)
+	assert.True(t, ok)
+	assert.Equal(t, filename, finished.Filename)
+	assert.Equal(t, backupFile(dir), finished.Backup)
+	assert.Equal(t, int64(len(b)), finished.Size)
+	assert.Equal(t, fakeTime(), finished.Time)
+}
+
+func TestEvents_CompressAndRemove(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	rec := &eventRecorder{}
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:   filename,
+		MaxBackups: 1,
+		Compress:   true,
+		OnEvent:    rec.onEvent,
+		OnError:    rec.onError,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+	first := backupFile(dir)
+
+	// we need to wait a little bit since the files get compressed on a
+	// different goroutine.
+	<-time.After(300 * time.Millisecond)
+
+	compressed, ok := rec.find(EventBackupCompressed)
+	assert.True(t, ok)
+	assert.Equal(t, first, compressed.Filename)
+	assert.Equal(t, first+compressSuffix, compressed.Backup)
+	assert.Greater(t, compressed.Size, int64(0))
+
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+	<-time.After(300 * time.Millisecond)
+
+	removed, ok := rec.find(EventBackupRemoved)
+	assert.True(t, ok)
+	assert.Equal(t, first+compressSuffix, removed.Filename)
+	assert.Equal(t, compressed.Size, removed.Size)
+	assert.Empty(t, rec.errs)
+}
+
+func TestEvents_MillError(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	rec := &eventRecorder{}
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+		Compress: true,
+		OnError:  rec.onError,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	// a directory in the way of the compressed file makes compression fail.
+	newFakeTime()
+	err = os.Mkdir(backupFile(dir)+compressSuffix, 0o700)
+	assert.Nil(t, err)
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	// we need to wait a little bit since the files get compressed on a
+	// different goroutine.
+	<-time.After(300 * time.Millisecond)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	assert.Equal(t, 1, len(rec.errs))
+}
+
+func TestEvents_HandlerMayWrite(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+		MaxSize:  10,
+	}
+	defer l.Close()
+	handled := false
+	l.OnEvent = func(e Event) {
+		if e.Kind == EventRotationFinished && !handled {
+			handled = true
+			_, err := l.Write([]byte("!"))
+			assert.Nil(t, err)
+		}
+	}
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	newFakeTime()
+	b2 := []byte("foooooo!")
+	n, err = l.Write(b2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b2), n)
+
+	// the handler ran after the write that triggered the rotation.
+	fileContainsContent(t, filename, []byte("foooooo!!"))
+}
+
+func TestEvents_HandlerMayWriteWhileClosing(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	written := make(chan error, 1)
+	var l *Logger
+	l = &Logger{
+		Filename: logFile(dir),
+		Compress: true,
+		OnEvent: func(e Event) {
+			if e.Kind == EventBackupCompressed {
+				// give Close time to start waiting for the mill.
+				<-time.After(100 * time.Millisecond)
+				_, err := l.Write([]byte("compressed!"))
+				written <- err
+			}
+		},
+	}
+
+	_, err := l.Write([]byte("boo!"))
+	assert.Nil(t, err)
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- l.Close()
+	}()
+	select {
+	case err := <-closed:
+		assert.Nil(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Close did not return")
+	}
+	assert.Equal(t, ErrClosing, <-written)
+}
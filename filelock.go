@@ -0,0 +1,17 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package woodcutter
+
+import (
+	"errors"
+	"os"
+)
+
+func lockFile(_ *os.File) error {
+	return errors.New("cross-process locking is not supported on this platform")
+}
+
+func unlockFile(_ *os.File) error {
+	return nil
+}
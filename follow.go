@@ -0,0 +1,335 @@
+package woodcutter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultPollInterval is how often a Follower checks for new data when
+	// none is given.
+	defaultPollInterval = 250 * time.Millisecond
+
+	// maxDrained is the number of files read to the end a Follower remembers
+	// to recognize their rotation events, which may come late.
+	maxDrained = 8
+)
+
+// ensure we always implement io.ReadCloser.
+var _ io.ReadCloser = (*Follower)(nil)
+
+// FollowOptions configures a Follower.
+type FollowOptions struct {
+	// FromStart makes the Follower read the log file from the beginning
+	// instead of only what is written after it was made.
+	FromStart bool
+
+	// PollInterval is how often the log file is checked for new data and for
+	// rotation.  The default is 250 milliseconds.
+	PollInterval time.Duration
+}
+
+// Follower is an io.ReadCloser that reads a log file as it is written, like
+// tail -F.  When the log file is rotated, the Follower reads what is left of
+// the backup before moving on to the new log file, so that nothing is lost or
+// read twice.  It works on the file alone, so it can follow a Logger in
+// another process; in the same process, pass events to OnEvent so that
+// rotations are noticed right away, and so that the backups of rotations that
+// happen before the Follower catches up are read too.
+//
+// If the log file is truncated in place, as with CopyTruncate, the Follower
+// starts over from the beginning of the file; whatever was written between its
+// last read and the truncation is missed.
+type Follower struct {
+	filename string
+	interval time.Duration
+
+	mu      sync.Mutex
+	file    *os.File
+	offset  int64
+	lines   *bufio.Reader
+	backlog *Reader
+
+	// rotated lists the backups OnEvent was told about that are not read
+	// yet, drained the last files read to the end, and caughtUp whether the
+	// rotation of one of those has been seen, after which the other
+	// backups in rotated were skipped over and must be read.
+	rotated  []rotatedBackup
+	drained  []os.FileInfo
+	caughtUp bool
+
+	wake      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// rotatedBackup is a backup the Follower was told about by OnEvent.
+type rotatedBackup struct {
+	name string
+	info os.FileInfo
+}
+
+// NewFollower returns a Follower of the log file filename.  The file doesn't
+// need to exist yet.
+func NewFollower(filename string, opts FollowOptions) (*Follower, error) {
+	f := &Follower{
+		filename: filename,
+		interval: opts.PollInterval,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	if f.interval <= 0 {
+		f.interval = defaultPollInterval
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	if f.file != nil && !opts.FromStart {
+		offset, err := f.file.Seek(0, io.SeekEnd)
+		if err != nil {
+			f.file.Close()
+			return nil, fmt.Errorf("can't seek log file: %w", err)
+		}
+		f.offset = offset
+	}
+	return f, nil
+}
+
+// OnEvent wakes the Follower up when the log file was rotated or reopened.
+// It can be used as Logger.OnEvent, or be called from it.
+func (f *Follower) OnEvent(e Event) {
+	switch e.Kind {
+	case EventRotationFinished:
+		// the backup may already have been compressed or moved by the mill,
+		// so the Logger records which file it is.
+		info := e.backupInfo
+		if info == nil {
+			if stat, err := osStat(e.Backup); err == nil {
+				info = stat
+			}
+		}
+		f.mu.Lock()
+		f.rotated = append(f.rotated, rotatedBackup{name: e.Backup, info: info})
+		f.mu.Unlock()
+	case EventReopened:
+	default:
+		return
+	}
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Read implements io.Reader.  It blocks until there is data to read, and
+// returns io.EOF once the Follower is closed.
+func (f *Follower) Read(p []byte) (int, error) {
+	for {
+		n, again, err := f.read(p)
+		if n > 0 || err != nil {
+			return n, err
+		}
+		if again {
+			continue
+		}
+
+		timer := time.NewTimer(f.interval)
+		select {
+		case <-f.done:
+			timer.Stop()
+			return 0, io.EOF
+		case <-f.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// ReadLine returns the next line, including its trailing newline, waiting
+// for it to be written.  Lines are read through a buffer, so ReadLine should
+// not be mixed with Read.
+func (f *Follower) ReadLine() ([]byte, error) {
+	if f.lines == nil {
+		f.lines = bufio.NewReader(f)
+	}
+	return f.lines.ReadBytes('\n')
+}
+
+// Close stops the Follower, making a pending Read return io.EOF, and closes
+// the file being followed.
+func (f *Follower) Close() error {
+	f.closeOnce.Do(func() { close(f.done) })
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var err error
+	if f.backlog != nil {
+		err = f.backlog.Close()
+		f.backlog = nil
+	}
+	if f.file != nil {
+		if closeErr := f.file.Close(); err == nil {
+			err = closeErr
+		}
+		f.file = nil
+	}
+	return err
+}
+
+// read reads from the file being followed.  At the end of the file, it checks
+// whether the log file was rotated or truncated, and reports whether to read
+// again right away.
+func (f *Follower) read(p []byte) (n int, again bool, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return 0, false, io.EOF
+	default:
+	}
+
+	if f.backlog != nil {
+		n, err = f.backlog.Read(p)
+		if errors.Is(err, io.EOF) {
+			err = f.backlog.Close()
+			f.backlog = nil
+			return n, n == 0 && err == nil, err
+		}
+		return n, false, err
+	}
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, false, err
+		}
+		return 0, f.file != nil, nil
+	}
+
+	if len(f.rotated) > 0 {
+		// read the backups of the rotations that happened before the log
+		// file was opened first.
+		current, err := f.file.Stat()
+		if err != nil {
+			return 0, false, fmt.Errorf("can't stat log file: %w", err)
+		}
+		if f.backlog = f.backlogBefore(current); f.backlog != nil {
+			return 0, true, nil
+		}
+	}
+
+	n, err = f.read1(p)
+	if n > 0 || err != nil {
+		return n, false, err
+	}
+
+	current, err := f.file.Stat()
+	if err != nil {
+		return 0, false, fmt.Errorf("can't stat log file: %w", err)
+	}
+	info, err := osStat(f.filename)
+	switch {
+	case err == nil && os.SameFile(info, current):
+		if info.Size() >= f.offset {
+			return 0, false, nil
+		}
+		// truncated in place.
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return 0, false, fmt.Errorf("can't seek log file: %w", err)
+		}
+		f.offset = 0
+		return 0, true, nil
+	case err != nil && !os.IsNotExist(err):
+		return 0, false, fmt.Errorf("can't stat log file: %w", err)
+	}
+
+	// rotated: read what was written to the backup before it was renamed,
+	// then go on with the new log file.
+	n, err = f.read1(p)
+	if n > 0 || err != nil {
+		return n, false, err
+	}
+	f.drained = append(f.drained, current)
+	if len(f.drained) > maxDrained {
+		f.drained = f.drained[1:]
+	}
+	err = f.file.Close()
+	f.file = nil
+	f.offset = 0
+	if err != nil {
+		return 0, false, fmt.Errorf("can't close log file: %w", err)
+	}
+	return 0, true, nil
+}
+
+// backlogBefore returns a Reader of the backups rotated after the last file
+// the Follower read to the end, and before current, the file being followed,
+// or nil if there are none.  The rotations of current and later ones are kept
+// until current has been read to the end.
+func (f *Follower) backlogBefore(current os.FileInfo) *Reader {
+	var r *Reader
+	for len(f.rotated) > 0 {
+		b := f.rotated[0]
+		switch {
+		case b.info != nil && os.SameFile(b.info, current):
+			return r
+		case f.wasDrained(b.info):
+			f.caughtUp = true
+		case f.caughtUp:
+			if r == nil {
+				r = &Reader{}
+			}
+			r.files = append(r.files, FileRange{Filename: b.name, End: -1})
+		}
+		// rotations from before the Follower was made are skipped.
+		f.rotated = f.rotated[1:]
+	}
+	return r
+}
+
+// wasDrained reports whether info is one of the last files the Follower read
+// to the end.
+func (f *Follower) wasDrained(info os.FileInfo) bool {
+	if info == nil {
+		return false
+	}
+	for _, drained := range f.drained {
+		if os.SameFile(info, drained) {
+			return true
+		}
+	}
+	return false
+}
+
+// read1 reads once from the file being followed, treating its end as no
+// data.
+func (f *Follower) read1(p []byte) (int, error) {
+	n, err := f.file.Read(p)
+	f.offset += int64(n)
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	if err != nil {
+		return n, fmt.Errorf("can't read log file: %w", err)
+	}
+	return n, nil
+}
+
+// open opens the log file, if it exists.
+func (f *Follower) open() error {
+	file, err := os.Open(f.filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't open log file: %w", err)
+	}
+	f.file = file
+	f.offset = 0
+	return nil
+}
@@ -0,0 +1,196 @@
+package woodcutter
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFollow_Rotate(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+	}
+	defer l.Close()
+
+	// an existing line that is skipped.
+	_, err := l.Write([]byte("zero\n"))
+	assert.Nil(t, err)
+
+	// only rotation events wake the follower up.
+	f, err := NewFollower(filename, FollowOptions{PollInterval: time.Hour})
+	assert.Nil(t, err)
+	defer f.Close()
+	l.OnEvent = f.OnEvent
+
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			line, err := f.ReadLine()
+			if err != nil {
+				close(lines)
+				return
+			}
+			lines <- string(line)
+		}
+	}()
+
+	_, err = l.Write([]byte("one\ntwo\n"))
+	assert.Nil(t, err)
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+	_, err = l.Write([]byte("three\n"))
+	assert.Nil(t, err)
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	for _, expected := range []string{"one\n", "two\n", "three\n"} {
+		select {
+		case line := <-lines:
+			assert.Equal(t, expected, line)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %q", expected)
+		}
+	}
+}
+
+func TestFollow_Poll(t *testing.T) {
+	dir := t.TempDir()
+
+	// an existing line that is skipped.
+	filename := logFile(dir)
+	err := os.WriteFile(filename, []byte("zero\n"), 0o644)
+	assert.Nil(t, err)
+
+	f, err := NewFollower(filename, FollowOptions{PollInterval: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	defer f.Close()
+
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0o644)
+	assert.Nil(t, err)
+	_, err = file.WriteString("one\n")
+	assert.Nil(t, err)
+
+	// rotate the file behind the follower's back, with a line written after
+	// the follower's last read.
+	err = os.Rename(filename, backupFile(dir))
+	assert.Nil(t, err)
+	_, err = file.WriteString("two\n")
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+	err = os.WriteFile(filename, []byte("three\n"), 0o644)
+	assert.Nil(t, err)
+
+	b := make([]byte, 0, 64)
+	for len(b) < len("one\ntwo\nthree\n") {
+		buf := make([]byte, 64)
+		n, err := f.Read(buf)
+		assert.Nil(t, err)
+		b = append(b, buf[:n]...)
+	}
+	assert.Equal(t, "one\ntwo\nthree\n", string(b))
+}
+
+func TestFollow_FromStartTruncate(t *testing.T) {
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	err := os.WriteFile(filename, []byte("one\n"), 0o644)
+	assert.Nil(t, err)
+
+	f, err := NewFollower(filename, FollowOptions{FromStart: true, PollInterval: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	defer f.Close()
+
+	line, err := f.ReadLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "one\n", string(line))
+
+	// truncated in place, as with CopyTruncate.
+	err = os.WriteFile(filename, []byte("x\n"), 0o644)
+	assert.Nil(t, err)
+
+	line, err = f.ReadLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "x\n", string(line))
+}
+
+func TestFollow_Close(t *testing.T) {
+	dir := t.TempDir()
+
+	// the log file doesn't exist yet.
+	f, err := NewFollower(logFile(dir), FollowOptions{})
+	assert.Nil(t, err)
+
+	done := make(chan error)
+	go func() {
+		_, err := f.Read(make([]byte, 10))
+		done <- err
+	}()
+
+	<-time.After(50 * time.Millisecond)
+	assert.Nil(t, f.Close())
+
+	select {
+	case err := <-done:
+		assert.Equal(t, io.EOF, err)
+	case <-time.After(time.Second):
+		t.Fatal("read didn't return after close")
+	}
+}
+
+func TestFollow_RotateTwiceCompressed(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+		Compress: true,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("zero\n"))
+	assert.Nil(t, err)
+
+	f, err := NewFollower(filename, FollowOptions{PollInterval: time.Hour})
+	assert.Nil(t, err)
+	defer f.Close()
+	l.OnEvent = f.OnEvent
+
+	// two rotations before the follower reads anything, the second backup
+	// being compressed by then.
+	_, err = l.Write([]byte("one\n"))
+	assert.Nil(t, err)
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+	_, err = l.Write([]byte("two\n"))
+	assert.Nil(t, err)
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+	_, err = l.Write([]byte("three\n"))
+	assert.Nil(t, err)
+
+	// we need to wait a little bit since the backups are compressed on a
+	// different goroutine.
+	<-time.After(300 * time.Millisecond)
+	assert.NoFileExists(t, backupFile(dir))
+
+	for _, expected := range []string{"one\n", "two\n", "three\n"} {
+		line, err := f.ReadLine()
+		assert.Nil(t, err)
+		assert.Equal(t, expected, string(line))
+	}
+}
@@ -7,6 +7,7 @@ require github.com/stretchr/testify v1.8.4
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/google/uuid v1.3.1
+	github.com/klauspost/compress v1.17.11
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
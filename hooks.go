@@ -0,0 +1,122 @@
+package woodcutter
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrClosing is returned by Write and Rotate while Close waits for the
+// OnRotate hooks to return, so that hooks can't reopen the log file.
+var ErrClosing = errors.New("logger is being closed")
+
+// RotatedFile describes a backup made by a rotation, for OnRotate.
+type RotatedFile struct {
+	// Filename is the log file that was rotated.
+	Filename string
+
+	// Backup is the name the log file was moved to.
+	Backup string
+
+	// Final is the name of the backup once the Logger is done with it: Backup
+	// itself, or the compressed file if Compress is set.  It is empty if the
+	// backup was removed, because of MaxAge for example, before the hook was
+	// called.
+	Final string
+
+	// Time is when the rotation happened.
+	Time time.Time
+}
+
+// queueRotated records a backup for OnRotate.  The hook is called by the next
+// run of the mill, once the backup has been compressed.
+func (l *Logger) queueRotated(file RotatedFile) {
+	if l.OnRotate == nil {
+		return
+	}
+	l.hookMu.Lock()
+	l.hookQueue = append(l.hookQueue, file)
+	l.hookMu.Unlock()
+}
+
+// renameRotated updates the queued backups after oldname has been renamed to
+// newname, such as when numbered backups are shifted.  It must be called with
+// l.millMu held.
+func (l *Logger) renameRotated(oldname, newname string) {
+	l.hookMu.Lock()
+	defer l.hookMu.Unlock()
+	for _, files := range [][]RotatedFile{l.hookQueue, l.hookTaken} {
+		for i := range files {
+			if files[i].Backup == oldname {
+				files[i].Backup = newname
+			}
+		}
+	}
+}
+
+// takeRotated returns the queued backups and empties the queue.  The backups
+// are still renamed by renameRotated until they are passed to runHooks.  It
+// must be called with l.millMu held.
+func (l *Logger) takeRotated() []RotatedFile {
+	l.hookMu.Lock()
+	defer l.hookMu.Unlock()
+	files := l.hookQueue
+	l.hookQueue = nil
+	l.hookTaken = files
+	return files
+}
+
+// runHooks calls OnRotate for each of the given backups on its own goroutine,
+// at most MaxConcurrentHooks at a time.  It must be called with l.millMu held,
+// after the mill is done with the backups, so that their final names are
+// known.
+func (l *Logger) runHooks(files []RotatedFile) {
+	l.hookMu.Lock()
+	l.hookTaken = nil
+	l.hookMu.Unlock()
+
+	if len(files) == 0 {
+		return
+	}
+	if l.hookSem == nil {
+		n := l.MaxConcurrentHooks
+		if n <= 0 {
+			n = 1
+		}
+		l.hookSem = make(chan struct{}, n)
+	}
+
+	for _, file := range files {
+		file.Final = finalName(file.Backup)
+		l.hookWg.Add(1)
+		go l.runHook(l.hookSem, file)
+	}
+}
+
+// runHook calls OnRotate once a slot is free in sem, and reports its error.
+func (l *Logger) runHook(sem chan struct{}, file RotatedFile) {
+	defer l.hookWg.Done()
+
+	sem <- struct{}{}
+	err := l.OnRotate(file)
+	<-sem
+
+	if err != nil {
+		l.report(fmt.Errorf("rotate hook failed for %s: %w", file.Backup, err))
+	}
+	l.deliver()
+}
+
+// finalName returns the name the backup ended up with after the mill, or an
+// empty string if it no longer exists.
+func finalName(backup string) string {
+	if _, err := osStat(backup); err == nil {
+		return backup
+	}
+	for _, suffix := range compressSuffixes {
+		if _, err := osStat(backup + suffix); err == nil {
+			return backup + suffix
+		}
+	}
+	return ""
+}
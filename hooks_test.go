@@ -0,0 +1,191 @@
+package woodcutter
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks_FinalName(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	var (
+		mu      sync.Mutex
+		rotated []RotatedFile
+	)
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+		Compress: true,
+		OnRotate: func(file RotatedFile) error {
+			mu.Lock()
+			defer mu.Unlock()
+			rotated = append(rotated, file)
+			return nil
+		},
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	// we need to wait a little bit since the hooks run on a different
+	// goroutine once the backup is compressed.
+	<-time.After(300 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, len(rotated))
+	assert.Equal(t, filename, rotated[0].Filename)
+	assert.Equal(t, backupFile(dir), rotated[0].Backup)
+	assert.Equal(t, backupFile(dir)+compressSuffix, rotated[0].Final)
+	assert.Equal(t, fakeTime(), rotated[0].Time)
+	assert.FileExists(t, rotated[0].Final)
+}
+
+func TestHooks_Error(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	rec := &eventRecorder{}
+	l := &Logger{
+		Filename: logFile(dir),
+		OnRotate: func(RotatedFile) error {
+			return errors.New("upload failed")
+		},
+		OnError: rec.onError,
+	}
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	// Close waits for the hook to return.
+	err = l.Close()
+	assert.Nil(t, err)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	assert.Equal(t, 1, len(rec.errs))
+	assert.ErrorContains(t, rec.errs[0], "upload failed")
+	assert.ErrorContains(t, rec.errs[0], backupFile(dir))
+}
+
+func TestHooks_Concurrency(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	var running, most, calls atomic.Int32
+	l := &Logger{
+		Filename:           logFile(dir),
+		MaxConcurrentHooks: 2,
+		OnRotate: func(RotatedFile) error {
+			n := running.Add(1)
+			for {
+				m := most.Load()
+				if n <= m || most.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			<-time.After(50 * time.Millisecond)
+			running.Add(-1)
+			calls.Add(1)
+			return nil
+		},
+	}
+
+	for i := 0; i < 5; i++ {
+		_, err := l.Write([]byte("boo!"))
+		assert.Nil(t, err)
+		newFakeTime()
+		err = l.Rotate()
+		assert.Nil(t, err)
+	}
+
+	err := l.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(5), calls.Load())
+	assert.LessOrEqual(t, most.Load(), int32(2))
+}
+
+func TestHooks_NumberedShift(t *testing.T) {
+	currentTime = fakeTime
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:        filename,
+		NumberedBackups: true,
+		OnRotate:        func(RotatedFile) error { return nil },
+	}
+	defer l.Close()
+
+	// queue a rotation without running the mill, as if it were busy.
+	l.queueRotated(RotatedFile{Filename: filename, Backup: filename + ".1"})
+	l.renameRotated(filename+".1", filename+".2")
+	assert.Equal(t, []RotatedFile{{Filename: filename, Backup: filename + ".2"}}, l.takeRotated())
+	assert.Empty(t, l.takeRotated())
+}
+
+func TestHooks_WriteWhileClosing(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	release := make(chan struct{})
+	written := make(chan error, 1)
+	var l *Logger
+	l = &Logger{
+		Filename:   logFile(dir),
+		BufferSize: 1024,
+		OnRotate: func(RotatedFile) error {
+			<-release
+			_, err := l.Write([]byte("late!"))
+			written <- err
+			return nil
+		},
+	}
+
+	_, err := l.Write([]byte("boo!"))
+	assert.Nil(t, err)
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	// the hook is running by now.
+	<-time.After(300 * time.Millisecond)
+	closed := make(chan error)
+	go func() {
+		closed <- l.Close()
+	}()
+	<-time.After(100 * time.Millisecond)
+	close(release)
+
+	assert.Equal(t, ErrClosing, <-written)
+	assert.Nil(t, <-closed)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	assert.Nil(t, l.file)
+	assert.Nil(t, l.bgStop)
+	assert.False(t, l.closing)
+}
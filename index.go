@@ -0,0 +1,319 @@
+package woodcutter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// indexSuffix is appended to the uncompressed name of a backup to make
+	// the name of its index.
+	indexSuffix = ".idx"
+
+	// defaultIndexInterval is the number of bytes between index entries when
+	// IndexInterval is not set.
+	defaultIndexInterval = 1024 * 1024
+)
+
+// fileIndex is the index of a backup, stored as JSON next to it.  Offsets are
+// in the uncompressed contents of the backup.
+type fileIndex struct {
+	// First and Last are the times of the first and last records with a
+	// timestamp.
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+
+	// Entries are the times of records at regular offsets, in order.
+	Entries []indexEntry `json:"entries"`
+}
+
+// indexEntry is the time of the record starting at Offset.
+type indexEntry struct {
+	Time   time.Time `json:"time"`
+	Offset int64     `json:"offset"`
+}
+
+// FileRange is the part of a log file that may hold records of a time range,
+// as found by FindRange.
+type FileRange struct {
+	// Filename is the backup, possibly compressed, or the log file.
+	Filename string
+
+	// Start and End are the offsets of the range in the uncompressed contents
+	// of the file.  End is -1 for the end of the file.
+	Start int64
+	End   int64
+
+	// First and Last are the times of the first and last records of the file,
+	// if it is indexed.
+	First time.Time
+	Last  time.Time
+}
+
+// indexName returns the name of the index of the backup f.
+func indexName(f logInfo) string {
+	return strings.TrimSuffix(f.path(), compressionSuffix(f.Name())) + indexSuffix
+}
+
+// indexInterval returns the number of bytes between index entries.
+func (l *Logger) indexInterval() int64 {
+	if l.IndexInterval <= 0 {
+		return defaultIndexInterval
+	}
+	return l.IndexInterval
+}
+
+// indexBackups writes the index of the uncompressed backups that don't have
+// one.  It must be called with l.millMu held.
+func (l *Logger) indexBackups(files []logInfo) error {
+	if !l.Index {
+		return nil
+	}
+	for _, f := range files {
+		if compressionSuffix(f.Name()) != "" {
+			continue
+		}
+		name := indexName(f)
+		if _, err := osStat(name); err == nil {
+			continue
+		}
+		idx, err := l.buildIndex(f.path())
+		if err != nil {
+			return err
+		}
+		if err := l.writeIndex(name, idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildIndex reads the backup filename and records the time of its records.
+func (l *Logger) buildIndex(filename string) (fileIndex, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fileIndex{}, fmt.Errorf("can't open backup to index: %w", err)
+	}
+	defer f.Close()
+
+	var (
+		idx    fileIndex
+		offset int64
+		next   int64
+	)
+	interval := l.indexInterval()
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadSlice('\n')
+		size := int64(len(line))
+		if errors.Is(err, bufio.ErrBufferFull) {
+			// only the start of a long record is needed for its time.
+			var rest []byte
+			line = append([]byte(nil), line...)
+			for errors.Is(err, bufio.ErrBufferFull) {
+				rest, err = r.ReadSlice('\n')
+				size += int64(len(rest))
+			}
+		}
+		if len(line) > 0 {
+			if t, ok := l.recordTime(line); ok {
+				if idx.First.IsZero() {
+					idx.First = t
+				}
+				idx.Last = t
+				if offset >= next {
+					idx.Entries = append(idx.Entries, indexEntry{Time: t, Offset: offset})
+					next = offset + interval
+				}
+			}
+			offset += size
+		}
+		if errors.Is(err, io.EOF) {
+			return idx, nil
+		}
+		if err != nil {
+			return fileIndex{}, fmt.Errorf("can't read backup to index: %w", err)
+		}
+	}
+}
+
+// writeIndex writes idx to name, through a temporary file so that readers
+// never see a partial index.
+func (l *Logger) writeIndex(name string, idx fileIndex) error {
+	b, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("can't encode index: %w", err)
+	}
+	tmp := name + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("can't open index: %w", err)
+	}
+	_, err = f.Write(b)
+	if err == nil && l.durable() {
+		err = fileSync(f)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("can't write index: %w", err)
+	}
+	return nil
+}
+
+// readIndex reads the index of the backup f, and reports whether there is
+// one.
+func readIndex(f logInfo) (fileIndex, bool) {
+	b, err := os.ReadFile(indexName(f))
+	if err != nil {
+		return fileIndex{}, false
+	}
+	var idx fileIndex
+	if err := json.Unmarshal(b, &idx); err != nil || idx.First.IsZero() {
+		return fileIndex{}, false
+	}
+	return idx, true
+}
+
+// moveIndex moves the index of the backup f along with it, to the backup
+// named dst.
+func moveIndex(f logInfo, dst string, durable bool) error {
+	src := indexName(f)
+	info, err := osStat(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't get index info: %w", err)
+	}
+	return moveFile(src, strings.TrimSuffix(dst, compressionSuffix(dst))+indexSuffix, info, durable)
+}
+
+// removeIndex removes the index of the backup f, if any.
+func removeIndex(f logInfo) error {
+	if err := os.Remove(indexName(f)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// recordTime returns the time of the record line, read from the start of the
+// line according to IndexTimeLayout, or else from its slog time field.
+func (l *Logger) recordTime(line []byte) (time.Time, bool) {
+	if l.IndexTimeLayout != "" {
+		return prefixTime(line, l.IndexTimeLayout)
+	}
+	if t, ok := slogTime(line); ok {
+		return t, true
+	}
+	return prefixTime(line, time.RFC3339Nano)
+}
+
+// prefixTime parses the time at the start of line with layout, which spans as
+// many space separated fields as the layout.
+func prefixTime(line []byte, layout string) (time.Time, bool) {
+	fields := strings.Count(layout, " ") + 1
+	end := 0
+	for i := 0; i < fields; i++ {
+		next := bytes.IndexAny(line[end:], " \t\n")
+		if next < 0 {
+			end = len(line)
+			break
+		}
+		end += next
+		if i < fields-1 {
+			end++
+		}
+	}
+	t, err := time.Parse(layout, string(bytes.TrimSpace(line[:end])))
+	return t, err == nil
+}
+
+// slogTime returns the time of a record written by log/slog, with the JSON or
+// the text handler.
+func slogTime(line []byte) (time.Time, bool) {
+	var value []byte
+	if i := bytes.Index(line, []byte(`"time":"`)); i >= 0 {
+		value = line[i+len(`"time":"`):]
+		if end := bytes.IndexByte(value, '"'); end >= 0 {
+			value = value[:end]
+		}
+	} else if i := bytes.Index(line, []byte("time=")); i == 0 || i > 0 && line[i-1] == ' ' {
+		value = line[i+len("time="):]
+		if end := bytes.IndexAny(value, " \n"); end >= 0 {
+			value = value[:end]
+		}
+	} else {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, string(value))
+	return t, err == nil
+}
+
+// FindRange returns the parts of the backups and of the log file that may
+// hold records from since to until, oldest first.  A zero since or until
+// leaves that end open.  Indexed backups, see Index, are narrowed down to the
+// offsets covering the range; the others are selected by the time span they
+// cover, from the rotation time of the previous backup to their own.
+func (l *Logger) FindRange(since, until time.Time) ([]FileRange, error) {
+	backups, err := l.oldLogFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ranges []FileRange
+		start  time.Time
+	)
+	opts := ReaderOptions{Since: since, Until: until}
+	for i := len(backups) - 1; i >= 0; i-- {
+		f := backups[i]
+		if idx, ok := readIndex(f); ok {
+			if r, ok := idx.find(since, until); ok {
+				r.Filename = f.path()
+				ranges = append(ranges, r)
+			}
+		} else if opts.inRange(start, f.timestamp) {
+			ranges = append(ranges, FileRange{Filename: f.path(), End: -1})
+		}
+		start = f.timestamp
+	}
+	if opts.inRange(start, currentTime()) {
+		ranges = append(ranges, FileRange{Filename: l.filename(), End: -1})
+	}
+	return ranges, nil
+}
+
+// find returns the range of the indexed file covering since to until, and
+// reports whether there is one.
+func (idx fileIndex) find(since, until time.Time) (FileRange, bool) {
+	if !since.IsZero() && idx.Last.Before(since) {
+		return FileRange{}, false
+	}
+	if !until.IsZero() && idx.First.After(until) {
+		return FileRange{}, false
+	}
+	r := FileRange{End: -1, First: idx.First, Last: idx.Last}
+	for _, e := range idx.Entries {
+		if !since.IsZero() && e.Time.Before(since) {
+			r.Start = e.Offset
+		}
+		if !until.IsZero() && e.Time.After(until) {
+			r.End = e.Offset
+			break
+		}
+	}
+	return r, true
+}
@@ -0,0 +1,170 @@
+package woodcutter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndex_FindRange(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	defer resetMocks()
+	dir := t.TempDir()
+
+	l := &Logger{
+		Filename:      logFile(dir),
+		MaxSize:       1000,
+		Compress:      true,
+		Index:         true,
+		IndexInterval: 1,
+	}
+	defer l.Close()
+
+	// the records must predate the rotation below.
+	start := fakeTime().UTC().Add(-time.Hour).Truncate(time.Second)
+	for i := 0; i < 5; i++ {
+		line := fmt.Sprintf(`{"time":%q,"msg":"%d"}`+"\n", start.Add(time.Duration(i)*time.Minute).Format(time.RFC3339), i)
+		_, err := l.Write([]byte(line))
+		assert.Nil(t, err)
+	}
+	newFakeTime()
+	err := l.Rotate()
+	assert.Nil(t, err)
+
+	// we need to wait a little bit since the index is written on a different
+	// goroutine.
+	<-time.After(300 * time.Millisecond)
+
+	assert.FileExists(t, backupFile(dir)+indexSuffix)
+	assert.FileExists(t, backupFile(dir)+compressSuffix)
+
+	ranges, err := l.FindRange(start.Add(90*time.Second), start.Add(150*time.Second))
+	assert.Nil(t, err)
+	// the log file was started after the range.
+	assert.Equal(t, 1, len(ranges))
+	assert.Equal(t, backupFile(dir)+compressSuffix, ranges[0].Filename)
+	assert.Equal(t, start, ranges[0].First)
+	assert.Equal(t, start.Add(4*time.Minute), ranges[0].Last)
+
+	r, err := NewReader(l, ReaderOptions{
+		Since: start.Add(90 * time.Second),
+		Until: start.Add(150 * time.Second),
+	})
+	assert.Nil(t, err)
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, fmt.Sprintf(`{"time":%q,"msg":"1"}`+"\n"+`{"time":%q,"msg":"2"}`+"\n",
+		start.Add(time.Minute).Format(time.RFC3339), start.Add(2*time.Minute).Format(time.RFC3339)), string(b))
+
+	// a range after the backup skips it.
+	ranges, err = l.FindRange(start.Add(time.Hour), time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(ranges))
+	assert.Equal(t, logFile(dir), ranges[0].Filename)
+}
+
+func TestIndex_RemoveWithBackup(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	l := &Logger{
+		Filename:   logFile(dir),
+		Index:      true,
+		MaxBackups: 1,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("time=2024-01-01T00:00:00Z msg=one\n"))
+	assert.Nil(t, err)
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+	<-time.After(300 * time.Millisecond)
+
+	first := backupFile(dir) + indexSuffix
+	assert.FileExists(t, first)
+
+	_, err = l.Write([]byte("time=2024-01-01T00:01:00Z msg=two\n"))
+	assert.Nil(t, err)
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+	<-time.After(300 * time.Millisecond)
+
+	assert.NoFileExists(t, first)
+	assert.FileExists(t, backupFile(dir)+indexSuffix)
+	// the log file, the backup and its index.
+	fileCount(t, dir, 3)
+}
+
+func TestIndex_Numbered(t *testing.T) {
+	currentTime = fakeTime
+	dir := t.TempDir()
+
+	l := &Logger{
+		Filename:        logFile(dir),
+		NumberedBackups: true,
+		Index:           true,
+	}
+	defer l.Close()
+
+	for _, line := range []string{"2024-01-01T00:00:00Z one\n", "2024-01-01T00:01:00Z two\n"} {
+		_, err := l.Write([]byte(line))
+		assert.Nil(t, err)
+		err = l.Rotate()
+		assert.Nil(t, err)
+		<-time.After(300 * time.Millisecond)
+	}
+
+	// the index moved along with the first backup.
+	idx, ok := readIndex(logInfo{dir: dir, DirEntry: dirEntry(t, filepath.Join(dir, "foobar.log.2"))})
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), idx.First)
+	assert.FileExists(t, filepath.Join(dir, "foobar.log.1"+indexSuffix))
+}
+
+func TestIndex_RecordTime(t *testing.T) {
+	expected := time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC)
+
+	l := &Logger{}
+	for _, line := range []string{
+		`{"time":"2024-01-01T12:30:00Z","level":"INFO","msg":"hi"}`,
+		`time=2024-01-01T12:30:00Z level=INFO msg=hi`,
+		`2024-01-01T12:30:00Z hi`,
+	} {
+		got, ok := l.recordTime([]byte(line))
+		assert.True(t, ok, line)
+		assert.True(t, expected.Equal(got), line)
+	}
+
+	_, ok := l.recordTime([]byte("no time here\n"))
+	assert.False(t, ok)
+
+	l.IndexTimeLayout = "2006/01/02 15:04:05"
+	got, ok := l.recordTime([]byte("2024/01/01 12:30:00 hi\n"))
+	assert.True(t, ok)
+	assert.True(t, expected.Equal(got))
+}
+
+// dirEntry returns the directory entry of filename.
+func dirEntry(t *testing.T, filename string) os.DirEntry {
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	assert.Nil(t, err)
+	for _, e := range entries {
+		if e.Name() == filepath.Base(filename) {
+			return e
+		}
+	}
+	t.Fatalf("%s not found", filename)
+	return nil
+}
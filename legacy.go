@@ -0,0 +1,62 @@
+package woodcutter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// legacyBackupTemplate is how lumberjack, which woodcutter is forked from,
+// names its backups: like woodcutter, but without the random suffix.
+const legacyBackupTemplate = "{name}-{time}{ext}"
+
+// legacyNaming returns the naming scheme of lumberjack backups of the current
+// log file, or nil if it is the same as the configured scheme.
+func (l *Logger) legacyNaming(naming *backupNaming) (*backupNaming, error) {
+	if naming.tmpl == legacyBackupTemplate && naming.layout == backupTimeFormat {
+		return nil, nil //nolint:nilnil // there is no separate legacy scheme
+	}
+	return newBackupNaming(legacyBackupTemplate, backupTimeFormat, l.filename())
+}
+
+// renameLegacyBackups renames the lumberjack backups of the current log file
+// into the configured naming scheme.  With numbered backups, they are numbered
+// after all the existing ones, since they predate them.
+func (l *Logger) renameLegacyBackups(naming *backupNaming) error {
+	oldNaming, err := l.legacyNaming(naming)
+	if err != nil || oldNaming == nil {
+		return err
+	}
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return err
+	}
+
+	var legacy []logInfo
+	maxSeq := 0
+	for _, f := range files {
+		if f.legacy {
+			legacy = append(legacy, f)
+		} else if f.seq > maxSeq {
+			maxSeq = f.seq
+		}
+	}
+	// newest first, so they get the lowest numbers.
+	sort.Sort(byFormatTime(legacy))
+
+	for i, f := range legacy {
+		_, suffix, err := oldNaming.parseFile(f.Name())
+		if err != nil {
+			return err
+		}
+
+		newname := naming.format(f.timestamp, maxSeq+i+1) + suffix
+		src := f.path()
+		dst := filepath.Join(f.dir, newname)
+		if err := os.Rename(src, dst); err != nil {
+			return fmt.Errorf("can't rename legacy backup: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package woodcutter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// legacyBackupFile returns the name lumberjack would give a backup made at the
+// current fake time.
+func legacyBackupFile(dir string) string {
+	return filepath.Join(dir, "foobar-"+fakeTime().UTC().Format(backupTimeFormat)+".log")
+}
+
+func TestLegacy_TimeFromName(t *testing.T) {
+	l := &Logger{Filename: "/var/log/myfoo/foo.log"}
+
+	got, err := l.timeFromName("foo-2014-05-04T14-44-33.555.log")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2014, 5, 4, 14, 44, 33, 555000000, time.UTC), got)
+
+	_, err = l.timeFromName("foo-2014-05-04T14-44-33.555.txt")
+	assert.NotNil(t, err)
+}
+
+func TestLegacy_OldLogFiles(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	data := []byte("data")
+	legacy := legacyBackupFile(dir)
+	err := os.WriteFile(legacy, data, 0o644)
+	assert.Nil(t, err)
+
+	newFakeTime()
+	legacyCompressed := legacyBackupFile(dir) + compressSuffix
+	err = os.WriteFile(legacyCompressed, data, 0o644)
+	assert.Nil(t, err)
+
+	newFakeTime()
+	backup := backupFile(dir)
+	err = os.WriteFile(backup, data, 0o644)
+	assert.Nil(t, err)
+
+	l := &Logger{Filename: logFile(dir)}
+	files, err := l.oldLogFiles()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(files))
+	assert.Equal(t, filepath.Base(backup), files[0].Name())
+	assert.False(t, files[0].legacy)
+	assert.Equal(t, filepath.Base(legacyCompressed), files[1].Name())
+	assert.True(t, files[1].legacy)
+	assert.Equal(t, filepath.Base(legacy), files[2].Name())
+	assert.True(t, files[2].legacy)
+}
+
+func TestLegacy_MaxBackups(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	dir := t.TempDir()
+
+	data := []byte("data")
+	legacy := legacyBackupFile(dir)
+	err := os.WriteFile(legacy, data, 0o644)
+	assert.Nil(t, err)
+
+	newFakeTime()
+	legacy2 := legacyBackupFile(dir)
+	err = os.WriteFile(legacy2, data, 0o644)
+	assert.Nil(t, err)
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:   filename,
+		MaxSize:    10,
+		MaxBackups: 2,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	// we need to wait a little bit since the files get deleted on a different
+	// goroutine.
+	<-time.After(10 * time.Millisecond)
+
+	// the oldest lumberjack backup no longer piles up.
+	assert.NoFileExists(t, legacy)
+	assert.FileExists(t, legacy2)
+	fileContainsContent(t, backupFile(dir), b)
+	fileCount(t, dir, 3)
+}
+
+func TestLegacy_Rename(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	data := []byte("data")
+	legacy := legacyBackupFile(dir)
+	err := os.WriteFile(legacy, data, 0o644)
+	assert.Nil(t, err)
+	renamed := backupFile(dir)
+
+	l := &Logger{
+		Filename:            logFile(dir),
+		RenameLegacyBackups: true,
+	}
+	err = l.millRunOnce()
+	assert.Nil(t, err)
+
+	assert.NoFileExists(t, legacy)
+	fileContainsContent(t, renamed, data)
+}
+
+func TestLegacy_RenameNumbered(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+	filename := logFile(dir)
+
+	older := legacyBackupFile(dir)
+	err := os.WriteFile(older, []byte("older"), 0o644)
+	assert.Nil(t, err)
+	newFakeTime()
+	newer := legacyBackupFile(dir) + compressSuffix
+	err = os.WriteFile(newer, []byte("newer"), 0o644)
+	assert.Nil(t, err)
+	err = os.WriteFile(filename+".1", []byte("numbered"), 0o644)
+	assert.Nil(t, err)
+
+	l := &Logger{
+		Filename:            filename,
+		NumberedBackups:     true,
+		RenameLegacyBackups: true,
+	}
+	err = l.millRunOnce()
+	assert.Nil(t, err)
+
+	// lumberjack backups are older than any numbered backup.
+	fileContainsContent(t, filename+".1", []byte("numbered"))
+	fileContainsContent(t, filename+".2"+compressSuffix, []byte("newer"))
+	fileContainsContent(t, filename+".3", []byte("older"))
+	fileCount(t, dir, 3)
+}
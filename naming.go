@@ -0,0 +1,235 @@
+package woodcutter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultBackupTemplate  = "{name}-{time}-{rand}{ext}"
+	numberedBackupTemplate = "{name}{ext}.{seq}"
+)
+
+// placeholderPattern matches the placeholders of a backup template.
+var placeholderPattern = regexp.MustCompile(`\{[a-z]+\}`)
+
+// backupFields holds the values a backup filename was rendered from.
+type backupFields struct {
+	timestamp time.Time
+	seq       int
+	values    map[string]string
+}
+
+// backupNaming renders and parses backup filenames according to a template.
+// See Logger.BackupTemplate for the supported placeholders.
+type backupNaming struct {
+	tmpl   string
+	layout string
+	name   string
+	ext    string
+
+	// re matches the names rendered from tmpl, with one capture group for each
+	// placeholder listed in groups.
+	re     *regexp.Regexp
+	groups []string
+}
+
+// newBackupNaming compiles tmpl for backups of the log file filename, using
+// layout to format and parse {time}.
+func newBackupNaming(tmpl, layout, filename string) (*backupNaming, error) {
+	if strings.ContainsAny(tmpl, `/\`) {
+		return nil, fmt.Errorf("invalid backup template %q: must not contain path separators", tmpl)
+	}
+	if strings.ContainsAny(layout, `/\`) {
+		return nil, fmt.Errorf("invalid backup time format %q: must not contain path separators", layout)
+	}
+
+	base := filepath.Base(filename)
+	ext := filepath.Ext(base)
+	n := &backupNaming{
+		tmpl:   tmpl,
+		layout: layout,
+		name:   base[:len(base)-len(ext)],
+		ext:    ext,
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringIndex(tmpl, -1) {
+		pattern.WriteString(regexp.QuoteMeta(tmpl[last:loc[0]]))
+		last = loc[1]
+
+		placeholder := tmpl[loc[0]+1 : loc[1]-1]
+		var expr string
+		switch placeholder {
+		case "name":
+			pattern.WriteString(regexp.QuoteMeta(n.name))
+			continue
+		case "ext":
+			pattern.WriteString(regexp.QuoteMeta(n.ext))
+			continue
+		case "time":
+			expr = layoutPattern(layout)
+		case "seq", "pid":
+			expr = `\d+`
+		case "host":
+			expr = `[A-Za-z0-9._-]+?`
+		case "rand":
+			expr = fmt.Sprintf(`[0-9a-f]{%d}`, randomSuffixLen)
+		default:
+			return nil, fmt.Errorf("invalid backup template %q: unknown placeholder {%s}", tmpl, placeholder)
+		}
+		pattern.WriteString("(" + expr + ")")
+		n.groups = append(n.groups, placeholder)
+	}
+	pattern.WriteString(regexp.QuoteMeta(tmpl[last:]))
+	pattern.WriteString("$")
+
+	if !n.has("time") && !n.has("seq") {
+		return nil, fmt.Errorf("invalid backup template %q: must contain {time} or {seq}", tmpl)
+	}
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup template %q: %w", tmpl, err)
+	}
+	n.re = re
+	return n, nil
+}
+
+// layoutPattern returns a regular expression matching the times formatted
+// with layout, treating runs of digits and letters as variable width fields.
+func layoutPattern(layout string) string {
+	var pattern strings.Builder
+	for i := 0; i < len(layout); {
+		j := i + 1
+		switch c := rune(layout[i]); {
+		case unicode.IsDigit(c):
+			for j < len(layout) && unicode.IsDigit(rune(layout[j])) {
+				j++
+			}
+			pattern.WriteString(`\d+`)
+		case unicode.IsLetter(c):
+			for j < len(layout) && unicode.IsLetter(rune(layout[j])) {
+				j++
+			}
+			pattern.WriteString(`[A-Za-z]+`)
+		default:
+			pattern.WriteString(regexp.QuoteMeta(layout[i:j]))
+		}
+		i = j
+	}
+	return pattern.String()
+}
+
+// has reports whether the template uses the given placeholder.
+func (n *backupNaming) has(placeholder string) bool {
+	for _, g := range n.groups {
+		if g == placeholder {
+			return true
+		}
+	}
+	return false
+}
+
+// numbered reports whether backups are numbered and must be shifted on each
+// rotation.
+func (n *backupNaming) numbered() bool {
+	return n.has("seq")
+}
+
+// format returns the filename of a backup rotated at t, with sequence number
+// seq.
+func (n *backupNaming) format(t time.Time, seq int) string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	return n.render(map[string]string{
+		"time": t.Format(n.layout),
+		"seq":  strconv.Itoa(seq),
+		"host": host,
+		"pid":  strconv.Itoa(os.Getpid()),
+		"rand": newUUID().String()[:randomSuffixLen], // first 4 bytes of UUID
+	})
+}
+
+// render substitutes values for the placeholders of the template.
+func (n *backupNaming) render(values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(n.tmpl, func(placeholder string) string {
+		switch key := placeholder[1 : len(placeholder)-1]; key {
+		case "name":
+			return n.name
+		case "ext":
+			return n.ext
+		default:
+			return values[key]
+		}
+	})
+}
+
+// parse extracts the fields of a backup filename rendered from the template.
+// An error means that the file was not generated by woodcutter.
+func (n *backupNaming) parse(filename string) (backupFields, error) {
+	m := n.re.FindStringSubmatch(filename)
+	if m == nil {
+		return backupFields{}, errors.New("name does not match backup template")
+	}
+
+	fields := backupFields{values: make(map[string]string, len(n.groups))}
+	for i, placeholder := range n.groups {
+		value := m[i+1]
+		switch placeholder {
+		case "time":
+			t, err := time.Parse(n.layout, value)
+			if err != nil {
+				return backupFields{}, err
+			}
+			fields.timestamp = t
+		case "seq":
+			seq, err := strconv.Atoi(value)
+			if err != nil || seq <= 0 {
+				return backupFields{}, fmt.Errorf("invalid sequence number %q", value)
+			}
+			fields.seq = seq
+		}
+		fields.values[placeholder] = value
+	}
+	return fields, nil
+}
+
+// parseFile extracts the fields of a backup filename like parse, also
+// accepting compressed backups.  It returns the compression suffix of the
+// file, if any.
+func (n *backupNaming) parseFile(filename string) (backupFields, string, error) {
+	fields, err := n.parse(filename)
+	if suffix := compressionSuffix(filename); err != nil && suffix != "" {
+		fields, err = n.parse(strings.TrimSuffix(filename, suffix))
+		return fields, suffix, err
+	}
+	return fields, "", err
+}
+
+// backupNaming returns the naming scheme for backups of the current log file.
+func (l *Logger) backupNaming() (*backupNaming, error) {
+	tmpl := l.BackupTemplate
+	if tmpl == "" {
+		tmpl = defaultBackupTemplate
+		if l.NumberedBackups {
+			tmpl = numberedBackupTemplate
+		}
+	}
+	layout := l.BackupTimeFormat
+	if layout == "" {
+		layout = backupTimeFormat
+	}
+	return newBackupNaming(tmpl, layout, l.filename())
+}
@@ -0,0 +1,152 @@
+package woodcutter
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNaming_RoundTrip(t *testing.T) {
+	newUUID = fakeUUID
+	host, err := os.Hostname()
+	assert.Nil(t, err)
+	pid := strconv.Itoa(os.Getpid())
+	rand := fakeUUID().String()[:randomSuffixLen]
+	when := time.Date(2023, 6, 14, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		tmpl   string
+		layout string
+		want   string
+	}{
+		{defaultBackupTemplate, backupTimeFormat, "foo-2023-06-14T10-30-15.000-" + rand + ".log"},
+		{numberedBackupTemplate, backupTimeFormat, "foo.log.3"},
+		{"{name}.{time}{ext}", "20060102-150405", "foo.20230614-103015.log"},
+		{"{name}{ext}-{time}", "Jan-02-2006", "foo.log-Jun-14-2023"},
+		{"{host}-{pid}-{name}-{time}{ext}", "2006-01-02", host + "-" + pid + "-foo-2023-06-14.log"},
+		{"{name}-{time}.{seq}{ext}", "2006-01-02", "foo-2023-06-14.3.log"},
+	}
+
+	for _, test := range tests {
+		naming, err := newBackupNaming(test.tmpl, test.layout, "/var/log/foo.log")
+		assert.Nil(t, err, test.tmpl)
+
+		name := naming.format(when, 3)
+		assert.Equal(t, test.want, name, test.tmpl)
+
+		fields, err := naming.parse(name)
+		assert.Nil(t, err, test.tmpl)
+		if naming.has("time") {
+			want, _ := time.Parse(test.layout, when.Format(test.layout))
+			assert.Equal(t, want, fields.timestamp, test.tmpl)
+		}
+		if naming.numbered() {
+			assert.Equal(t, 3, fields.seq, test.tmpl)
+		}
+		assert.Equal(t, name, naming.render(fields.values), test.tmpl)
+
+		_, err = naming.parse("foo.log")
+		assert.NotNil(t, err, test.tmpl)
+
+		// backups of other log files are not recognized.
+		other, err := newBackupNaming(test.tmpl, test.layout, "/var/log/bar.log")
+		assert.Nil(t, err, test.tmpl)
+		_, err = other.parse(name)
+		assert.NotNil(t, err, test.tmpl)
+	}
+}
+
+func TestNaming_Invalid(t *testing.T) {
+	for _, tmpl := range []string{
+		"{name}{ext}",
+		"{name}-{rand}{ext}",
+		"{name}-{time}{ext}.{nope}",
+		"{time}/{name}{ext}",
+	} {
+		_, err := newBackupNaming(tmpl, backupTimeFormat, "foo.log")
+		assert.NotNil(t, err, tmpl)
+	}
+	for _, layout := range []string{"2006/01/02", `2006\01\02`} {
+		_, err := newBackupNaming(defaultBackupTemplate, layout, "foo.log")
+		assert.NotNil(t, err, layout)
+	}
+
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+	l := &Logger{
+		Filename:       logFile(dir),
+		BackupTemplate: "{name}{ext}",
+	}
+	defer l.Close()
+	n, err := l.Write([]byte("boo!"))
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, n)
+
+	// a bad time format is rejected before the file is opened.
+	l2 := &Logger{
+		Filename:         logFile(dir),
+		BackupTimeFormat: "2006/01/02",
+	}
+	defer l2.Close()
+	n, err = l2.Write([]byte("boo!"))
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, n)
+	assert.NoFileExists(t, logFile(dir))
+}
+
+func TestNaming_CustomTemplate(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	dir := t.TempDir()
+
+	const layout = "20060102-150405"
+	backup := func() string {
+		return filepath.Join(dir, "foobar.log."+fakeTime().UTC().Format(layout))
+	}
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:         filename,
+		MaxSize:          10,
+		MaxBackups:       1,
+		BackupTemplate:   "{name}{ext}.{time}",
+		BackupTimeFormat: layout,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	newFakeTime()
+	b2 := []byte("foooooo!")
+	n, err = l.Write(b2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b2), n)
+	first := backup()
+	fileContainsContent(t, first, b)
+
+	newFakeTime()
+	b3 := []byte("baaaaar!")
+	n, err = l.Write(b3)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b3), n)
+	second := backup()
+	fileContainsContent(t, second, b2)
+
+	// we need to wait a little bit since the files get deleted on a different
+	// goroutine.
+	<-time.After(10 * time.Millisecond)
+
+	// the older backup is recognized from the template and removed.
+	assert.NoFileExists(t, first)
+	fileContainsContent(t, filename, b3)
+	fileCount(t, dir, 2)
+}
@@ -0,0 +1,50 @@
+package woodcutter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// shiftBackups renames every numbered backup with sequence number n
+// (compressed or not) to n+1, oldest first, so that sequence number 1 is free
+// for the file being rotated out.
+func (l *Logger) shiftBackups(naming *backupNaming) error {
+	// don't move files the mill is working on.
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].seq > files[j].seq
+	})
+
+	for _, f := range files {
+		if f.legacy || f.seq == 0 {
+			// lumberjack backups aren't numbered; renameLegacyBackups
+			// numbers them after the others.
+			continue
+		}
+		fields, suffix, err := naming.parseFile(f.Name())
+		if err != nil {
+			return err
+		}
+		fields.values["seq"] = strconv.Itoa(fields.seq + 1)
+
+		src := f.path()
+		dst := filepath.Join(f.dir, naming.render(fields.values)+suffix)
+		if err := os.Rename(src, dst); err != nil {
+			return fmt.Errorf("can't shift numbered backup: %w", err)
+		}
+		if err := moveIndex(f, dst, false); err != nil {
+			return err
+		}
+		l.renameRotated(src, dst)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package woodcutter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumbered_SeqFromName(t *testing.T) {
+	l := &Logger{Filename: "/var/log/myfoo/foo.log", NumberedBackups: true}
+	naming, err := l.backupNaming()
+	assert.Nil(t, err)
+
+	tests := []struct {
+		filename string
+		want     int
+		suffix   string
+		wantErr  bool
+	}{
+		{"foo.log.1", 1, "", false},
+		{"foo.log.12", 12, "", false},
+		{"foo.log.3.gz", 3, compressSuffix, false},
+		{"foo.log.0", 0, "", true},
+		{"foo.log.x", 0, "", true},
+		{"foo.log", 0, "", true},
+		{"bar.log.1", 0, "", true},
+	}
+
+	for _, test := range tests {
+		fields, suffix, err := naming.parseFile(test.filename)
+		assert.Equal(t, test.want, fields.seq, test.filename)
+		assert.Equal(t, test.wantErr, err != nil, test.filename)
+		if !test.wantErr {
+			assert.Equal(t, test.suffix, suffix, test.filename)
+		}
+	}
+}
+
+func TestNumbered_Rotate(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:        filename,
+		MaxSize:         10,
+		NumberedBackups: true,
+	}
+	defer l.Close()
+
+	for _, b := range [][]byte{[]byte("one!"), []byte("twooooo!"), []byte("threeee!"), []byte("fouuuur!")} {
+		n, err := l.Write(b)
+		assert.Nil(t, err)
+		assert.Equal(t, len(b), n)
+	}
+
+	fileContainsContent(t, filename, []byte("fouuuur!"))
+	fileContainsContent(t, filename+".1", []byte("threeee!"))
+	fileContainsContent(t, filename+".2", []byte("twooooo!"))
+	fileContainsContent(t, filename+".3", []byte("one!"))
+	fileCount(t, dir, 4)
+
+	files, err := l.oldLogFiles()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(files))
+	for i, f := range files {
+		assert.Equal(t, i+1, f.seq)
+	}
+}
+
+func TestNumbered_MaxBackups(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:        filename,
+		MaxSize:         10,
+		MaxBackups:      2,
+		NumberedBackups: true,
+	}
+	defer l.Close()
+
+	for _, b := range [][]byte{[]byte("one!"), []byte("twooooo!"), []byte("threeee!"), []byte("fouuuur!")} {
+		n, err := l.Write(b)
+		assert.Nil(t, err)
+		assert.Equal(t, len(b), n)
+	}
+
+	// we need to wait a little bit since the files get deleted on a different
+	// goroutine.
+	<-time.After(10 * time.Millisecond)
+
+	fileContainsContent(t, filename+".1", []byte("threeee!"))
+	fileContainsContent(t, filename+".2", []byte("twooooo!"))
+	assert.NoFileExists(t, filename+".3")
+	fileCount(t, dir, 3)
+}
+
+func TestNumbered_Compress(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:        filename,
+		MaxSize:         10,
+		Compress:        true,
+		NumberedBackups: true,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	// we need to wait a little bit since the files get compressed on a
+	// different goroutine.
+	<-time.After(300 * time.Millisecond)
+
+	err = l.Rotate()
+	assert.Nil(t, err)
+	<-time.After(300 * time.Millisecond)
+
+	// the compressed backup was shifted along.
+	bc := new(bytes.Buffer)
+	gz := gzip.NewWriter(bc)
+	_, err = gz.Write(b)
+	assert.Nil(t, err)
+	err = gz.Close()
+	assert.Nil(t, err)
+	fileContainsContent(t, filename+".2"+compressSuffix, bc.Bytes())
+	assert.FileExists(t, filename+".1"+compressSuffix)
+	assert.NoFileExists(t, filename+".1")
+	assert.NoFileExists(t, filename+".2")
+	fileCount(t, dir, 3)
+}
+
+func TestNumbered_IgnoresOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	filename := logFile(dir)
+
+	for _, name := range []string{"foobar.log.1", "foobar.log.2.gz", "foobar.log.old", "foobar.log.1.bak"} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte("data"), 0o644)
+		assert.Nil(t, err)
+	}
+
+	l := &Logger{Filename: filename, NumberedBackups: true}
+	files, err := l.oldLogFiles()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(files))
+	assert.Equal(t, "foobar.log.1", files[0].Name())
+	assert.Equal(t, "foobar.log.2.gz", files[1].Name())
+}
+
+func TestNumbered_RotateWithLegacy(t *testing.T) {
+	for _, rename := range []bool{false, true} {
+		currentTime = fakeTime
+		newUUID = fakeUUID
+		dir := t.TempDir()
+
+		filename := logFile(dir)
+		legacy := legacyBackupFile(dir)
+		err := os.WriteFile(legacy, []byte("legacy"), 0o644)
+		assert.Nil(t, err)
+
+		l := &Logger{
+			Filename:            filename,
+			NumberedBackups:     true,
+			RenameLegacyBackups: rename,
+		}
+
+		for _, b := range [][]byte{[]byte("one!"), []byte("two!")} {
+			_, err = l.Write(b)
+			assert.Nil(t, err)
+			err = l.Rotate()
+			assert.Nil(t, err, "rename: %v", rename)
+			<-time.After(10 * time.Millisecond)
+		}
+		assert.Nil(t, l.Close())
+
+		fileContainsContent(t, filename+".1", []byte("two!"))
+		fileContainsContent(t, filename+".2", []byte("one!"))
+		if rename {
+			// the lumberjack backup is numbered after the others.
+			assert.NoFileExists(t, legacy)
+			fileContainsContent(t, filename+".3", []byte("legacy"))
+		} else {
+			fileContainsContent(t, legacy, []byte("legacy"))
+			assert.NoFileExists(t, filename+".3")
+		}
+		fileCount(t, dir, 4)
+	}
+}
@@ -0,0 +1,91 @@
+package woodcutter
+
+import (
+	"fmt"
+)
+
+// Values of Logger.OversizeMode.
+const (
+	OversizeError     = "error"
+	OversizeDedicated = "dedicated"
+	OversizeSplit     = "split"
+	OversizeTruncate  = "truncate"
+)
+
+// truncatedMarker ends writes truncated because of OversizeMode.
+const truncatedMarker = "...[truncated]\n"
+
+// checkOversizeMode returns an error if OversizeMode is not a known mode.
+func (l *Logger) checkOversizeMode() error {
+	switch l.OversizeMode {
+	case "", OversizeError, OversizeDedicated, OversizeSplit, OversizeTruncate:
+		return nil
+	default:
+		return fmt.Errorf("unknown oversize mode %q", l.OversizeMode)
+	}
+}
+
+// writeOversize handles a write larger than MaxSize according to OversizeMode.
+func (l *Logger) writeOversize(p []byte) (int, error) {
+	if err := l.checkOversizeMode(); err != nil {
+		return 0, err
+	}
+
+	var (
+		n   int
+		err error
+	)
+	switch l.OversizeMode {
+	case OversizeDedicated:
+		// rotates unless the file is empty, and the next write rotates again
+		// since the file is over MaxSize.
+		n, err = l.writeFile(p)
+	case OversizeSplit:
+		n, err = l.writeSplit(p)
+	case OversizeTruncate:
+		_, err = l.writeFile(truncate(p, l.max()))
+		if err == nil {
+			n = len(p)
+		}
+	default:
+		return 0, fmt.Errorf(
+			"write length %d exceeds maximum file size %d", len(p), l.max(),
+		)
+	}
+	if err != nil {
+		return n, err
+	}
+
+	l.notify(Event{
+		Kind:     EventOversizeWrite,
+		Filename: l.filename(),
+		Size:     int64(len(p)),
+		Mode:     l.OversizeMode,
+	})
+	return n, nil
+}
+
+// writeSplit writes p in chunks of MaxSize, each in a file of its own.
+func (l *Logger) writeSplit(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		end := written + int(min(int64(len(p)-written), l.max()))
+		n, err := l.writeFile(p[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// truncate returns the first limit bytes of p, ending with truncatedMarker
+// when it fits.
+func truncate(p []byte, limit int64) []byte {
+	if limit < int64(len(truncatedMarker)) {
+		return p[:limit]
+	}
+	out := make([]byte, 0, limit)
+	out = append(out, p[:limit-int64(len(truncatedMarker))]...)
+	return append(out, truncatedMarker...)
+}
@@ -0,0 +1,129 @@
+package woodcutter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOversize_Dedicated(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	dir := t.TempDir()
+
+	rec := &eventRecorder{}
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:     filename,
+		MaxSize:      10,
+		OversizeMode: OversizeDedicated,
+		OnEvent:      rec.onEvent,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	newFakeTime()
+	big := []byte("a stack trace that is too long")
+	n, err = l.Write(big)
+	assert.Nil(t, err)
+	assert.Equal(t, len(big), n)
+	fileContainsContent(t, backupFile(dir), b)
+	fileContainsContent(t, filename, big)
+
+	// the next write goes to a new file.
+	newFakeTime()
+	b2 := []byte("foo!")
+	n, err = l.Write(b2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b2), n)
+	fileContainsContent(t, backupFile(dir), big)
+	fileContainsContent(t, filename, b2)
+	fileCount(t, dir, 3)
+
+	oversize, ok := rec.find(EventOversizeWrite)
+	assert.True(t, ok)
+	assert.Equal(t, filename, oversize.Filename)
+	assert.Equal(t, int64(len(big)), oversize.Size)
+	assert.Equal(t, OversizeDedicated, oversize.Mode)
+}
+
+func TestOversize_Split(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:        filename,
+		MaxSize:         10,
+		NumberedBackups: true,
+		OversizeMode:    OversizeSplit,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	big := []byte("0123456789abcdefghijKLMNO")
+	n, err = l.Write(big)
+	assert.Nil(t, err)
+	assert.Equal(t, len(big), n)
+
+	fileContainsContent(t, filename+".3", b)
+	fileContainsContent(t, filename+".2", []byte("0123456789"))
+	fileContainsContent(t, filename+".1", []byte("abcdefghij"))
+	existsWithSize(t, filename, 5)
+	fileContainsContent(t, filename, []byte("KLMNO"))
+	fileCount(t, dir, 4)
+}
+
+func TestOversize_Truncate(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:     filename,
+		MaxSize:      20,
+		OversizeMode: OversizeTruncate,
+	}
+	defer l.Close()
+
+	big := []byte(strings.Repeat("x", 30))
+	n, err := l.Write(big)
+	assert.Nil(t, err)
+	assert.Equal(t, len(big), n)
+
+	existsWithSize(t, filename, 20)
+	fileContainsContent(t, filename, []byte("xxxxx"+truncatedMarker))
+
+	// a limit smaller than the marker cuts the write without one.
+	assert.Equal(t, []byte("xxx"), truncate(big, 3))
+}
+
+func TestOversize_InvalidMode(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	l := &Logger{
+		Filename:     logFile(dir),
+		OversizeMode: "compress",
+	}
+	defer l.Close()
+
+	n, err := l.Write([]byte("boo!"))
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, n)
+	assert.NoFileExists(t, logFile(dir))
+}
@@ -0,0 +1,152 @@
+package woodcutter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// partitionLayout returns the components of BackupDirLayout, one for each
+// level of subdirectories, or an error if it isn't a relative path.
+func (l *Logger) partitionLayout() ([]string, error) {
+	if l.BackupDirLayout == "" {
+		return nil, nil
+	}
+	layout := filepath.ToSlash(l.BackupDirLayout)
+	if strings.HasPrefix(layout, "/") || filepath.IsAbs(l.BackupDirLayout) {
+		return nil, fmt.Errorf("invalid backup directory layout %q: must be relative", l.BackupDirLayout)
+	}
+	components := strings.Split(layout, "/")
+	for _, c := range components {
+		if c == "" || c == "." || c == ".." {
+			return nil, fmt.Errorf("invalid backup directory layout %q: bad path element %q", l.BackupDirLayout, c)
+		}
+	}
+	return components, nil
+}
+
+// partitionPatterns returns a regular expression for each level of
+// subdirectories of BackupDirLayout, matching the names of that level.
+func (l *Logger) partitionPatterns() ([]*regexp.Regexp, error) {
+	components, err := l.partitionLayout()
+	if err != nil {
+		return nil, err
+	}
+	patterns := make([]*regexp.Regexp, len(components))
+	for i, c := range components {
+		patterns[i] = regexp.MustCompile("^" + layoutPattern(c) + "$")
+	}
+	return patterns, nil
+}
+
+// backupDir returns the directory a backup rotated at t belongs in.
+func (l *Logger) backupDir(t time.Time) string {
+	if l.BackupDirLayout == "" {
+		return l.archiveDir()
+	}
+	return filepath.Join(l.archiveDir(), filepath.FromSlash(t.Format(filepath.ToSlash(l.BackupDirLayout))))
+}
+
+// backupsUnder returns the backups in dir and, recursively, in its
+// subdirectories matching the given levels of BackupDirLayout.
+func (l *Logger) backupsUnder(dir string, levels []*regexp.Regexp, naming, legacy *backupNaming) ([]logInfo, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	logFiles := l.backupsIn(dir, files, naming, legacy)
+	if len(levels) == 0 {
+		return logFiles, nil
+	}
+	for _, f := range files {
+		if !f.IsDir() || !levels[0].MatchString(f.Name()) {
+			continue
+		}
+		sub, err := l.backupsUnder(filepath.Join(dir, f.Name()), levels[1:], naming, legacy)
+		if os.IsNotExist(err) {
+			// pruned by another process.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		logFiles = append(logFiles, sub...)
+	}
+	return logFiles, nil
+}
+
+// notePrunable records that backups were removed from or moved out of dir, so
+// that pruneBackupDirs removes it if it is left empty.  It must be called with
+// l.millMu held.
+func (l *Logger) notePrunable(dir string) {
+	if l.BackupDirLayout == "" {
+		return
+	}
+	if l.prunable == nil {
+		l.prunable = make(map[string]bool)
+	}
+	l.prunable[dir] = true
+}
+
+// pruneBackupDirs removes the subdirectories of BackupDirLayout that were left
+// empty once their backups were removed or moved, along with their parents if
+// they are empty too.  It must be called with l.millMu held.
+func (l *Logger) pruneBackupDirs() error {
+	dirs := l.prunable
+	l.prunable = nil
+	components, err := l.partitionLayout()
+	if err != nil || len(components) == 0 {
+		return err
+	}
+	root := l.archiveDir()
+	for dir := range dirs {
+		for isPartitionDir(root, components, dir) {
+			if empty, err := isEmptyDir(dir); err != nil || !empty {
+				break
+			}
+			if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("can't remove backup directory: %w", err)
+			}
+			dir = filepath.Dir(dir)
+		}
+	}
+	return nil
+}
+
+// isPartitionDir reports whether dir is a subdirectory of root named after the
+// components of BackupDirLayout, as backupDir names them.
+func isPartitionDir(root string, components []string, dir string) bool {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	names := strings.Split(filepath.ToSlash(rel), "/")
+	if len(names) > len(components) {
+		return false
+	}
+	for i, name := range names {
+		t, err := time.Parse(components[i], name)
+		if err != nil || t.Format(components[i]) != name {
+			return false
+		}
+	}
+	return true
+}
+
+// isEmptyDir reports whether dir has no entries.
+func isEmptyDir(dir string) (bool, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	_, err = f.Readdirnames(1)
+	if err == io.EOF {
+		return true, nil
+	}
+	return false, err
+}
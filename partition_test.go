@@ -0,0 +1,187 @@
+package woodcutter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartition_MoveBackup(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	rec := &eventRecorder{}
+	l := &Logger{
+		Filename:        logFile(dir),
+		BackupDirLayout: "2006/01/02",
+		OnEvent:         rec.onEvent,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	// we need to wait a little bit since the backup is moved on a different
+	// goroutine.
+	<-time.After(300 * time.Millisecond)
+
+	// the log file and the year directory.
+	fileCount(t, dir, 2)
+	fileContainsContent(t, backupFile(partitionDir(dir)), b)
+
+	e, ok := rec.find(EventBackupArchived)
+	assert.True(t, ok)
+	assert.Equal(t, backupFile(dir), e.Filename)
+	assert.Equal(t, backupFile(partitionDir(dir)), e.Backup)
+}
+
+func TestPartition_MaxBackups(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	// two partitioned backups, on different days.
+	data := []byte("data")
+	oldest := partitionDir(dir)
+	err := os.MkdirAll(oldest, 0o755)
+	assert.Nil(t, err)
+	err = os.WriteFile(backupFile(oldest), data, 0o644)
+	assert.Nil(t, err)
+	newFakeTime()
+	newer := backupFile(partitionDir(dir))
+	err = os.MkdirAll(filepath.Dir(newer), 0o755)
+	assert.Nil(t, err)
+	err = os.WriteFile(newer, data, 0o644)
+	assert.Nil(t, err)
+	newFakeTime()
+
+	l := &Logger{
+		Filename:        logFile(dir),
+		BackupDirLayout: "2006/01/02",
+		MaxBackups:      2,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	err = l.Rotate()
+	assert.Nil(t, err)
+
+	<-time.After(300 * time.Millisecond)
+
+	files, err := l.oldLogFiles()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(files))
+	assert.FileExists(t, newer)
+	fileContainsContent(t, backupFile(partitionDir(dir)), b)
+
+	// the day directory of the removed backup is gone.
+	assert.NoDirExists(t, oldest)
+}
+
+func TestPartition_PruneOwnDirsOnly(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive")
+
+	// empty directories the Logger didn't make, some named like partitions.
+	others := []string{
+		filepath.Join(dir, "nginx"),
+		filepath.Join(dir, "2019"),
+		filepath.Join(archive, "Oct"),
+		filepath.Join(archive, "2019", "1"),
+	}
+	for _, other := range others {
+		err := os.MkdirAll(other, 0o755)
+		assert.Nil(t, err)
+	}
+
+	for _, layout := range []string{"Jan/02", "2006/01/02"} {
+		l := &Logger{
+			Filename:        logFile(dir),
+			ArchiveDir:      archive,
+			BackupDirLayout: layout,
+			MaxBackups:      1,
+		}
+		for i := 0; i < 3; i++ {
+			_, err := l.Write([]byte("boo!"))
+			assert.Nil(t, err)
+			newFakeTime()
+			err = l.Rotate()
+			assert.Nil(t, err)
+			<-time.After(100 * time.Millisecond)
+		}
+		assert.Nil(t, l.Close())
+
+		files, err := l.oldLogFiles()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(files), layout)
+	}
+
+	for _, other := range others {
+		assert.DirExists(t, other)
+	}
+}
+
+func TestPartition_IgnoreOtherDirs(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	// a backup-like file in a directory that doesn't match the layout.
+	other := filepath.Join(dir, "other")
+	err := os.Mkdir(other, 0o755)
+	assert.Nil(t, err)
+	err = os.WriteFile(backupFile(other), []byte("data"), 0o644)
+	assert.Nil(t, err)
+
+	partition := partitionDir(dir)
+	err = os.MkdirAll(partition, 0o755)
+	assert.Nil(t, err)
+	err = os.WriteFile(backupFile(partition), []byte("data"), 0o644)
+	assert.Nil(t, err)
+
+	l := &Logger{
+		Filename:        logFile(dir),
+		BackupDirLayout: "2006/01/02",
+	}
+	defer l.Close()
+
+	files, err := l.oldLogFiles()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, backupFile(partition), files[0].path())
+}
+
+func TestPartition_InvalidLayout(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, layout := range []string{"/2006", "2006/../01", "2006//01"} {
+		l := &Logger{
+			Filename:        logFile(dir),
+			BackupDirLayout: layout,
+		}
+		_, err := l.Write([]byte("boo!"))
+		assert.NotNil(t, err, layout)
+		l.Close()
+	}
+}
+
+// partitionDir returns the day directory under dir for the current fake time.
+func partitionDir(dir string) string {
+	return filepath.Join(dir, fakeTime().UTC().Format("2006/01/02"))
+}
@@ -0,0 +1,119 @@
+package woodcutter
+
+import (
+	"bytes"
+	"time"
+)
+
+// RotationState describes the current log file at the time a RotationPolicy
+// is consulted.
+type RotationState struct {
+	// Size is the size in bytes of the current log file.
+	Size int64
+
+	// OpenedAt is the time at which the Logger opened the current log file.
+	// For a file that already existed, this is when the Logger reopened it,
+	// not when it was created.
+	OpenedAt time.Time
+
+	// Writes is the number of writes made to the current log file since it
+	// was opened.
+	Writes int64
+
+	// Lines is the number of newlines written to the current log file since
+	// it was opened.
+	Lines int64
+
+	// Now is the current time.
+	Now time.Time
+}
+
+// RotationPolicy decides when a Logger rotates its log file.  ShouldRotate is
+// called before each write with the state of the current log file and the
+// bytes about to be written, and reports whether the file should be rotated
+// first.  It is called with the Logger's lock held, so it must not call back
+// into the Logger.
+type RotationPolicy interface {
+	ShouldRotate(state RotationState, p []byte) bool
+}
+
+// RotationPolicyFunc adapts an ordinary function to a RotationPolicy.
+type RotationPolicyFunc func(state RotationState, p []byte) bool
+
+// ShouldRotate implements RotationPolicy by calling f.
+func (f RotationPolicyFunc) ShouldRotate(state RotationState, p []byte) bool {
+	return f(state, p)
+}
+
+// SizePolicy rotates when a write would make the log file larger than
+// maxBytes.  It is the policy used when Logger.Policy is not set, with a limit
+// of MaxSize megabytes.
+func SizePolicy(maxBytes int64) RotationPolicy {
+	return RotationPolicyFunc(func(state RotationState, p []byte) bool {
+		return state.Size+int64(len(p)) > maxBytes
+	})
+}
+
+// AgePolicy rotates when the log file has been open for at least maxAge.
+func AgePolicy(maxAge time.Duration) RotationPolicy {
+	return RotationPolicyFunc(func(state RotationState, _ []byte) bool {
+		return state.Now.Sub(state.OpenedAt) >= maxAge
+	})
+}
+
+// LineCountPolicy rotates when a write would make the log file hold more than
+// maxLines lines.
+func LineCountPolicy(maxLines int64) RotationPolicy {
+	return RotationPolicyFunc(func(state RotationState, p []byte) bool {
+		return state.Lines+int64(bytes.Count(p, []byte{'\n'})) > maxLines
+	})
+}
+
+// AnyPolicy rotates when at least one of the given policies would rotate, for
+// example "at 50MB or every 6h, whichever comes first".
+func AnyPolicy(policies ...RotationPolicy) RotationPolicy {
+	return RotationPolicyFunc(func(state RotationState, p []byte) bool {
+		for _, policy := range policies {
+			if policy.ShouldRotate(state, p) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// AllPolicy rotates only when every one of the given policies would rotate.
+// It never rotates if no policies are given.
+func AllPolicy(policies ...RotationPolicy) RotationPolicy {
+	return RotationPolicyFunc(func(state RotationState, p []byte) bool {
+		for _, policy := range policies {
+			if !policy.ShouldRotate(state, p) {
+				return false
+			}
+		}
+		return len(policies) > 0
+	})
+}
+
+// policy returns the configured RotationPolicy, defaulting to MaxSize.
+func (l *Logger) policy() RotationPolicy {
+	if l.Policy != nil {
+		return l.Policy
+	}
+	return SizePolicy(l.max())
+}
+
+// shouldRotate consults the rotation policy about writing p to the current
+// file.  An empty file is never rotated.
+func (l *Logger) shouldRotate(size int64, p []byte) bool {
+	if size == 0 {
+		return false
+	}
+	return l.policy().ShouldRotate(RotationState{
+		Size:     size,
+		OpenedAt: l.openedAt,
+		Writes:   l.writes,
+		Lines:    l.lines,
+		Now:      currentTime(),
+	}, p)
+}
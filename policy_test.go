@@ -0,0 +1,134 @@
+package woodcutter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_BuiltIn(t *testing.T) {
+	now := time.Date(2023, 6, 14, 10, 30, 0, 0, time.UTC)
+	state := RotationState{
+		Size:     90,
+		OpenedAt: now.Add(-time.Hour),
+		Writes:   9,
+		Lines:    9,
+		Now:      now,
+	}
+	line := []byte("0123456789\n")
+
+	assert.True(t, SizePolicy(100).ShouldRotate(state, line))
+	assert.False(t, SizePolicy(101).ShouldRotate(state, line))
+
+	assert.True(t, AgePolicy(time.Hour).ShouldRotate(state, line))
+	assert.False(t, AgePolicy(2*time.Hour).ShouldRotate(state, line))
+
+	assert.True(t, LineCountPolicy(9).ShouldRotate(state, line))
+	assert.False(t, LineCountPolicy(10).ShouldRotate(state, line))
+
+	assert.True(t, AnyPolicy(SizePolicy(1000), AgePolicy(time.Hour)).ShouldRotate(state, line))
+	assert.False(t, AnyPolicy(SizePolicy(1000), AgePolicy(2*time.Hour)).ShouldRotate(state, line))
+	assert.False(t, AnyPolicy().ShouldRotate(state, line))
+
+	assert.True(t, AllPolicy(SizePolicy(100), AgePolicy(time.Hour)).ShouldRotate(state, line))
+	assert.False(t, AllPolicy(SizePolicy(1000), AgePolicy(time.Hour)).ShouldRotate(state, line))
+	assert.False(t, AllPolicy().ShouldRotate(state, line))
+}
+
+func TestPolicy_LineCount(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+		Policy:   LineCountPolicy(2),
+	}
+	defer l.Close()
+
+	for _, line := range []string{"one\n", "two\n"} {
+		n, err := l.Write([]byte(line))
+		assert.Nil(t, err)
+		assert.Equal(t, len(line), n)
+	}
+	fileCount(t, dir, 1)
+
+	newFakeTime()
+
+	b := []byte("three\n")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	fileContainsContent(t, backupFile(dir), []byte("one\ntwo\n"))
+	fileContainsContent(t, filename, b)
+	fileCount(t, dir, 2)
+}
+
+func TestPolicy_SizeOrAge(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+		Policy:   AnyPolicy(SizePolicy(10), AgePolicy(6*time.Hour)),
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	// neither limit is reached yet.
+	setFakeTime(fakeTime().Add(time.Hour))
+	n, err = l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+	fileCount(t, dir, 1)
+
+	// the age limit is reached first.
+	setFakeTime(fakeTime().Add(5 * time.Hour))
+	n, err = l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+	fileContainsContent(t, backupFile(dir), []byte("boo!boo!"))
+	fileCount(t, dir, 2)
+
+	// then the size limit, well within the age limit.
+	setFakeTime(fakeTime().Add(time.Minute))
+	b2 := []byte("foooooo!")
+	n, err = l.Write(b2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b2), n)
+	fileContainsContent(t, backupFile(dir), b)
+	fileContainsContent(t, filename, b2)
+	fileCount(t, dir, 3)
+}
+
+func TestPolicy_NoSizeLimit(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	defer resetMocks()
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+		MaxSize:  5,
+		Policy:   LineCountPolicy(100),
+	}
+	defer l.Close()
+
+	// MaxSize does not apply when a policy is set.
+	b := []byte("booooooooooooooo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+	fileContainsContent(t, filename, b)
+}
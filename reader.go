@@ -0,0 +1,182 @@
+package woodcutter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ensure we always implement io.ReadCloser.
+var _ io.ReadCloser = (*Reader)(nil)
+
+// ReaderOptions restricts the files a Reader reads.  Files are selected by
+// the time span they cover, from the rotation time of the previous backup to
+// their own rotation time, or by their index if they have one, so the first
+// and last file read may hold lines outside of the bounds.  See FindRange.
+type ReaderOptions struct {
+	// Since skips the backups rotated before Since.  The zero value reads
+	// from the oldest backup.
+	Since time.Time
+
+	// Until skips the files started after Until.  The zero value reads up to
+	// and including the current log file.
+	Until time.Time
+}
+
+// Reader is an io.ReadCloser that reads the backups of a Logger, oldest first,
+// followed by its current log file, as one stream.  Compressed backups are
+// decompressed as they are read.  Writes still held in the Logger's buffer are
+// not seen until it is flushed.
+type Reader struct {
+	files []FileRange
+
+	file         *os.File
+	decompressor io.ReadCloser
+	current      io.Reader
+	lines        *bufio.Reader
+}
+
+// NewReader returns a Reader of the files of l selected by opts.  The files
+// are listed when the Reader is made, and each is opened once the previous one
+// has been read.
+func NewReader(l *Logger, opts ReaderOptions) (*Reader, error) {
+	files, err := l.FindRange(opts.Since, opts.Until)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{files: files}, nil
+}
+
+// inRange reports whether a file covering the time from start to end is
+// selected.
+func (o ReaderOptions) inRange(start, end time.Time) bool {
+	if !o.Since.IsZero() && end.Before(o.Since) {
+		return false
+	}
+	if !o.Until.IsZero() && !start.IsZero() && start.After(o.Until) {
+		return false
+	}
+	return true
+}
+
+// Read implements io.Reader.  It returns io.EOF once the last file has been
+// read.
+func (r *Reader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.files) == 0 {
+				return 0, io.EOF
+			}
+			if err := r.openNext(); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		n, err := r.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			if closeErr := r.closeCurrent(); closeErr != nil {
+				return n, closeErr
+			}
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// ReadLine returns the next line, including its trailing newline if any.  It
+// returns io.EOF once the last file has been read.  Lines are read through a
+// buffer, so ReadLine should not be mixed with Read.
+func (r *Reader) ReadLine() ([]byte, error) {
+	if r.lines == nil {
+		r.lines = bufio.NewReader(r)
+	}
+	line, err := r.lines.ReadBytes('\n')
+	if len(line) > 0 && errors.Is(err, io.EOF) {
+		return line, nil
+	}
+	return line, err
+}
+
+// Close closes the file being read.
+func (r *Reader) Close() error {
+	r.files = nil
+	return r.closeCurrent()
+}
+
+// openNext opens the next file to read, skipping the files that were removed
+// since the Reader was made.
+func (r *Reader) openNext() error {
+	file := r.files[0]
+	r.files = r.files[1:]
+	name := file.Filename
+
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		// the backup may have been compressed in the meantime.
+		final := finalName(name)
+		if final == "" {
+			return nil
+		}
+		name = final
+		f, err = os.Open(name)
+		if os.IsNotExist(err) {
+			return nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("can't open log file: %w", err)
+	}
+
+	r.file = f
+	r.current = f
+	if suffix := compressionSuffix(name); suffix != "" {
+		d, err := newDecompressor(suffix, f)
+		if err != nil {
+			r.closeCurrent()
+			return fmt.Errorf("can't decompress %s: %w", name, err)
+		}
+		r.decompressor = d
+		r.current = d
+	}
+
+	if file.Start > 0 {
+		var err error
+		if r.decompressor != nil {
+			_, err = io.CopyN(io.Discard, r.current, file.Start)
+		} else {
+			_, err = f.Seek(file.Start, io.SeekStart)
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			r.closeCurrent()
+			return fmt.Errorf("can't skip to offset %d of %s: %w", file.Start, name, err)
+		}
+	}
+	if file.End >= 0 {
+		r.current = io.LimitReader(r.current, file.End-file.Start)
+	}
+	return nil
+}
+
+// closeCurrent closes the file being read, if any.
+func (r *Reader) closeCurrent() error {
+	if r.current == nil {
+		return nil
+	}
+	var err error
+	if r.decompressor != nil {
+		err = r.decompressor.Close()
+	}
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	r.file = nil
+	r.decompressor = nil
+	r.current = nil
+	return err
+}
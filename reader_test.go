@@ -0,0 +1,139 @@
+package woodcutter
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReader_ReadAll(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	l := &Logger{
+		Filename: logFile(dir),
+		Compress: true,
+	}
+	defer l.Close()
+
+	for _, line := range []string{"one\n", "two\n", "three\n"} {
+		_, err := l.Write([]byte(line))
+		assert.Nil(t, err)
+		newFakeTime()
+		err = l.Rotate()
+		assert.Nil(t, err)
+	}
+	_, err := l.Write([]byte("four\n"))
+	assert.Nil(t, err)
+
+	// we need to wait a little bit since the backups are compressed on a
+	// different goroutine.
+	<-time.After(300 * time.Millisecond)
+
+	r, err := NewReader(l, ReaderOptions{})
+	assert.Nil(t, err)
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "one\ntwo\nthree\nfour\n", string(b))
+}
+
+func TestReader_Compression(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	for _, compression := range []string{CompressionGzip, CompressionZstd, CompressionZlib} {
+		l := &Logger{
+			Filename:    logFile(dir),
+			Compress:    true,
+			Compression: compression,
+		}
+		_, err := l.Write([]byte(compression + "\n"))
+		assert.Nil(t, err)
+		newFakeTime()
+		err = l.Rotate()
+		assert.Nil(t, err)
+		<-time.After(300 * time.Millisecond)
+		assert.Nil(t, l.Close())
+	}
+
+	r, err := NewReader(&Logger{Filename: logFile(dir)}, ReaderOptions{})
+	assert.Nil(t, err)
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "gzip\nzstd\nzlib\n", string(b))
+}
+
+func TestReader_TimeRange(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	l := &Logger{
+		Filename: logFile(dir),
+	}
+	defer l.Close()
+
+	var rotations []time.Time
+	for _, line := range []string{"one\n", "two\n", "three\n"} {
+		_, err := l.Write([]byte(line))
+		assert.Nil(t, err)
+		newFakeTime()
+		rotations = append(rotations, fakeTime())
+		err = l.Rotate()
+		assert.Nil(t, err)
+	}
+	_, err := l.Write([]byte("four\n"))
+	assert.Nil(t, err)
+
+	// the second file covers the time from the first rotation to the second.
+	r, err := NewReader(l, ReaderOptions{
+		Since: rotations[0].Add(time.Hour),
+		Until: rotations[1].Add(-time.Hour),
+	})
+	assert.Nil(t, err)
+	defer r.Close()
+
+	line, err := r.ReadLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "two\n", string(line))
+
+	_, err = r.ReadLine()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReader_ReadLine(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	l := &Logger{
+		Filename: logFile(dir),
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("one\ntwo"))
+	assert.Nil(t, err)
+
+	r, err := NewReader(l, ReaderOptions{})
+	assert.Nil(t, err)
+	defer r.Close()
+
+	line, err := r.ReadLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "one\n", string(line))
+
+	line, err = r.ReadLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "two", string(line))
+
+	_, err = r.ReadLine()
+	assert.Equal(t, io.EOF, err)
+}
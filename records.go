@@ -0,0 +1,104 @@
+package woodcutter
+
+import (
+	"bytes"
+)
+
+// defaultMaxRecordSize is the size in bytes beyond which a pending record is
+// written even though it is incomplete, when MaxRecordSize is not set.
+const defaultMaxRecordSize = 64 * 1024
+
+// recordDelimiter returns the delimiter between records.
+func (l *Logger) recordDelimiter() []byte {
+	if l.RecordDelimiter == "" {
+		return []byte{'\n'}
+	}
+	return []byte(l.RecordDelimiter)
+}
+
+// maxRecordSize returns the maximum size in bytes of a pending record.
+func (l *Logger) maxRecordSize() int {
+	if l.MaxRecordSize <= 0 {
+		return defaultMaxRecordSize
+	}
+	return l.MaxRecordSize
+}
+
+// writeRecords writes the records completed by p, and holds back the rest of p
+// until it is completed by a later write.
+func (l *Logger) writeRecords(p []byte) (int, error) {
+	held := len(l.partial)
+	l.partial = append(l.partial, p...)
+
+	complete := 0
+	delim := l.recordDelimiter()
+	if end := bytes.LastIndex(l.partial, delim); end >= 0 {
+		complete = end + len(delim)
+	}
+	if len(l.partial)-complete > l.maxRecordSize() {
+		// give up on finding the end of the record.
+		complete = len(l.partial)
+	}
+
+	written, err := l.writeWhole(l.partial[:complete])
+	if err != nil {
+		l.partial = nil
+		return max(0, written-held), err
+	}
+	n := copy(l.partial, l.partial[complete:])
+	l.partial = l.partial[:n]
+	return len(p), nil
+}
+
+// writeWhole writes b, made of whole records, rotating between records when
+// needed.
+func (l *Logger) writeWhole(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		n, err := l.write(b[written : written+l.recordsThatFit(b[written:])])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// recordsThatFit returns the length of the longest run of records at the
+// start of b that can be written without rotating, or of the first record if
+// it doesn't fit by itself.
+func (l *Logger) recordsThatFit(b []byte) int {
+	fits := func(b []byte) bool {
+		if l.Policy == nil && int64(len(b)) > l.max() {
+			return false
+		}
+		return !l.shouldRotate(l.size, b)
+	}
+	if fits(b) {
+		return len(b)
+	}
+
+	delim := l.recordDelimiter()
+	fit := 0
+	for fit < len(b) {
+		next := len(b)
+		if i := bytes.Index(b[fit:], delim); i >= 0 {
+			next = fit + i + len(delim)
+		}
+		if fit > 0 && !fits(b[:next]) {
+			break
+		}
+		fit = next
+	}
+	return fit
+}
+
+// writePartial writes the pending incomplete record, if any.
+func (l *Logger) writePartial() error {
+	if len(l.partial) == 0 {
+		return nil
+	}
+	_, err := l.write(l.partial)
+	l.partial = nil
+	return err
+}
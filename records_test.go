@@ -0,0 +1,162 @@
+package woodcutter
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecords_HoldPartial(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:     filename,
+		MaxSize:      10,
+		WholeRecords: true,
+	}
+	defer l.Close()
+
+	b := []byte("abc")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+	assert.NoFileExists(t, filename)
+
+	b2 := []byte("de\nfg")
+	n, err = l.Write(b2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b2), n)
+	existsWithSize(t, filename, 6)
+
+	// the completed record doesn't fit, so it goes to a new file as a whole.
+	newFakeTime()
+	b3 := []byte("hijk\n")
+	n, err = l.Write(b3)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b3), n)
+
+	fileContainsContent(t, backupFile(dir), []byte("abcde\n"))
+	existsWithSize(t, filename, 7)
+	fileContainsContent(t, filename, []byte("fghijk\n"))
+	fileCount(t, dir, 2)
+}
+
+func TestRecords_SplitBetweenRecords(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:     filename,
+		MaxSize:      10,
+		WholeRecords: true,
+	}
+	defer l.Close()
+
+	// larger than MaxSize, but made of records that fit.
+	b := []byte("aaaa\nbbbb\ncccc\n")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	fileContainsContent(t, backupFile(dir), []byte("aaaa\nbbbb\n"))
+	existsWithSize(t, filename, 5)
+	fileContainsContent(t, filename, []byte("cccc\n"))
+	fileCount(t, dir, 2)
+}
+
+func TestRecords_MaxRecordSize(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:        filename,
+		WholeRecords:    true,
+		RecordDelimiter: "\x00",
+		MaxRecordSize:   4,
+	}
+	defer l.Close()
+
+	b := []byte("abc\x00de")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+	existsWithSize(t, filename, 4)
+
+	// the pending record grows too large, so it is written as is.
+	b2 := []byte("fgh")
+	n, err = l.Write(b2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b2), n)
+	existsWithSize(t, filename, 9)
+	fileContainsContent(t, filename, []byte("abc\x00defgh"))
+}
+
+func TestRecords_WritePartialOnClose(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:     filename,
+		WholeRecords: true,
+	}
+
+	b := []byte("boo!\nfoo")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+	existsWithSize(t, filename, 5)
+
+	err = l.Close()
+	assert.Nil(t, err)
+	fileContainsContent(t, filename, b)
+}
+
+// existsWithSize checks that the given file exists and has the given size.
+func existsWithSize(t *testing.T, path string, size int64) {
+	t.Helper()
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	if err == nil {
+		assert.Equal(t, size, info.Size())
+	}
+}
+
+func TestRecords_CloseRotatesPartial(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+	defer resetMocks()
+	dir := t.TempDir()
+
+	l := &Logger{
+		Filename:     logFile(dir),
+		MaxSize:      10,
+		WholeRecords: true,
+		BufferSize:   1024,
+	}
+
+	_, err := l.Write([]byte("12345678\nabcd"))
+	assert.Nil(t, err)
+
+	// the partial record doesn't fit, so Close rotates before writing it.
+	err = l.Close()
+	assert.Nil(t, err)
+	fileContainsContent(t, logFile(dir), []byte("abcd"))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	assert.Nil(t, l.file)
+	assert.Nil(t, l.bgStop)
+}
@@ -0,0 +1,69 @@
+package woodcutter
+
+import (
+	"fmt"
+	"os"
+)
+
+// Reopen checks whether the log file has been moved or deleted since the
+// Logger opened it, by logrotate or an operator for example, and if so closes
+// it and opens the file now at its path, sending EventReopened.  It can be
+// called when receiving a signal, such as SIGHUP, from a tool that moved the
+// file.  See also ReopenInterval.
+func (l *Logger) Reopen() error {
+	defer l.deliver()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	return l.reopenIfMoved()
+}
+
+// openAppend opens the log file for appending, creating it if needed.  It
+// never moves an existing file aside, since other processes may be writing to
+// it; rotation is left to rotateDue.
+func (l *Logger) openAppend() error {
+	if err := os.MkdirAll(l.dir(), 0o755); err != nil {
+		return fmt.Errorf("can't make directories for new logfile: %w", err)
+	}
+	const permissions = 0o600
+	f, err := os.OpenFile(l.filename(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, permissions)
+	if err != nil {
+		return fmt.Errorf("can't open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("can't get log file info: %w", err)
+	}
+	l.setFile(f)
+	l.size = info.Size()
+	l.resetCounters()
+	l.scheduleFrom(info.ModTime())
+	return nil
+}
+
+// reopenIfMoved reopens the log file if it has been moved or deleted, by
+// another process rotating it for example, and sends EventReopened.  Otherwise
+// it updates the size of the file, since other processes may write to it.
+func (l *Logger) reopenIfMoved() error {
+	ours, err := l.file.Stat()
+	if err != nil {
+		return fmt.Errorf("can't get log file info: %w", err)
+	}
+	info, err := osStat(l.filename())
+	if err == nil && os.SameFile(info, ours) {
+		return l.refreshSize()
+	}
+
+	if err := l.close(); err != nil {
+		return err
+	}
+	if err := l.openAppend(); err != nil {
+		return err
+	}
+	l.notify(Event{Kind: EventReopened, Filename: l.filename(), Size: l.size})
+	return nil
+}
@@ -0,0 +1,87 @@
+package woodcutter
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReopen_Moved(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	rec := &eventRecorder{}
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+		OnEvent:  rec.onEvent,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	// nothing happened to the file yet.
+	err = l.Reopen()
+	assert.Nil(t, err)
+	assert.Empty(t, rec.kinds())
+
+	moved := filename + ".1"
+	err = os.Rename(filename, moved)
+	assert.Nil(t, err)
+
+	err = l.Reopen()
+	assert.Nil(t, err)
+	assert.Equal(t, []EventKind{EventReopened}, rec.kinds())
+
+	b2 := []byte("foo!")
+	n, err = l.Write(b2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b2), n)
+	fileContainsContent(t, moved, b)
+	existsWithSize(t, filename, int64(len(b2)))
+	fileContainsContent(t, filename, b2)
+}
+
+func TestReopen_DeletedInBackground(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	rec := &eventRecorder{}
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:       filename,
+		ReopenInterval: 10 * time.Millisecond,
+		OnEvent:        rec.onEvent,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	err = os.Remove(filename)
+	assert.Nil(t, err)
+
+	// we need to wait a little bit since the check happens on a different
+	// goroutine.
+	<-time.After(100 * time.Millisecond)
+
+	reopened, ok := rec.find(EventReopened)
+	assert.True(t, ok)
+	assert.Equal(t, filename, reopened.Filename)
+	existsWithSize(t, filename, 0)
+
+	b2 := []byte("foo!")
+	n, err = l.Write(b2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b2), n)
+	fileContainsContent(t, filename, b2)
+}
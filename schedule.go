@@ -0,0 +1,262 @@
+package woodcutter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	cronFields   = 5
+	maxCronYears = 5
+	dayLength    = 24 * time.Hour
+)
+
+// schedule computes wall-clock rotation boundaries.
+type schedule interface {
+	// next returns the first boundary strictly after t, in t's location.  It
+	// returns the zero time if there is no such boundary.
+	next(t time.Time) time.Time
+}
+
+// parseSchedule parses the Logger's Schedule setting.  It accepts the keywords
+// "hourly", "daily" and "weekly" (optionally prefixed with "@"), a duration
+// understood by time.ParseDuration, or a five-field cron expression.
+func parseSchedule(spec string) (schedule, error) {
+	spec = strings.TrimSpace(spec)
+	switch strings.TrimPrefix(spec, "@") {
+	case "hourly":
+		spec = "0 * * * *"
+	case "daily", "midnight":
+		spec = "0 0 * * *"
+	case "weekly":
+		spec = "0 0 * * 0"
+	}
+
+	if d, err := time.ParseDuration(spec); err == nil {
+		if d <= 0 {
+			return nil, fmt.Errorf("invalid schedule %q: interval must be positive", spec)
+		}
+		if d > dayLength {
+			return nil, fmt.Errorf("invalid schedule %q: interval must be at most 24h", spec)
+		}
+		return intervalSchedule{every: d}, nil
+	}
+
+	s, err := parseCron(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	return s, nil
+}
+
+// intervalSchedule places a boundary every interval of wall-clock time,
+// counting from midnight of each day, and at midnight.  The boundaries are at
+// the same times every day, even when the day is shorter or longer because of
+// a daylight saving time change.
+type intervalSchedule struct {
+	every time.Duration
+}
+
+func (s intervalSchedule) next(t time.Time) time.Time {
+	elapsed := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	for k := elapsed/s.every + 1; ; k++ {
+		offset := k * s.every
+		if offset >= dayLength {
+			// the last interval of the day is cut short at midnight.
+			return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		}
+		// time.Date normalizes the offset in wall-clock terms.
+		next := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, int(offset), t.Location())
+		if next.After(t) {
+			return next
+		}
+	}
+}
+
+// cronSchedule is a parsed cron expression of the form
+// "minute hour day-of-month month day-of-week".
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domAny and dowAny record whether the day fields were "*", which changes
+	// how they are combined, as in cron(8).
+	domAny, dowAny bool
+}
+
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != cronFields {
+		return nil, fmt.Errorf("expected %d cron fields, got %d", cronFields, len(fields))
+	}
+
+	var (
+		s   cronSchedule
+		err error
+	)
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	// both 0 and 7 mean Sunday.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = fields[2] == "*"
+	s.dowAny = fields[4] == "*"
+	return &s, nil
+}
+
+// parseCronField parses a comma separated list of "*", "n", "n-m", each
+// optionally followed by "/step", into a bit set.
+func parseCronField(field string, lo, hi int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+
+		start, end := lo, hi
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var errA, errB error
+			start, errA = strconv.Atoi(a)
+			end, errB = strconv.Atoi(b)
+			if errA != nil || errB != nil {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", rng)
+			}
+			start = n
+			if step == 1 {
+				end = n
+			}
+		}
+		if start < lo || end > hi || start > end {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, lo, hi)
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	if bits == 0 {
+		return 0, errors.New("empty field")
+	}
+	return bits, nil
+}
+
+func (s *cronSchedule) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+	limit := t.AddDate(maxCronYears, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether t satisfies the day-of-month and day-of-week
+// fields.  When both are restricted, matching either is enough.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// loadSchedule parses the Schedule setting if it changed since it was last
+// parsed.
+func (l *Logger) loadSchedule() error {
+	if l.Schedule == l.schedSpec {
+		return nil
+	}
+	if l.Schedule == "" {
+		l.sched, l.schedSpec = nil, ""
+		return nil
+	}
+	s, err := parseSchedule(l.Schedule)
+	if err != nil {
+		return err
+	}
+	l.sched, l.schedSpec = s, l.Schedule
+	return nil
+}
+
+// scheduleTime converts t to the location used for schedule boundaries.
+func (l *Logger) scheduleTime(t time.Time) time.Time {
+	if l.LocalTime {
+		return t.Local()
+	}
+	return t.UTC()
+}
+
+// scheduleFrom sets the next scheduled rotation to the first boundary after t
+// and makes sure the background goroutine is running to honor it.
+func (l *Logger) scheduleFrom(t time.Time) {
+	if l.sched == nil {
+		l.nextRotate = time.Time{}
+		return
+	}
+	l.nextRotate = l.sched.next(l.scheduleTime(t))
+	l.startBackground()
+}
+
+// scheduleDue reports whether a schedule boundary has passed since the current
+// file was opened.
+func (l *Logger) scheduleDue() bool {
+	return !l.nextRotate.IsZero() && !currentTime().Before(l.nextRotate)
+}
+
+// scheduleDueSince reports whether a schedule boundary has passed since t.
+func (l *Logger) scheduleDueSince(t time.Time) bool {
+	if l.sched == nil {
+		return false
+	}
+	next := l.sched.next(l.scheduleTime(t))
+	return !next.IsZero() && !currentTime().Before(next)
+}
@@ -0,0 +1,206 @@
+package woodcutter
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// 2023-06-14 is a Wednesday.
+	now := time.Date(2023, 6, 14, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"hourly", time.Date(2023, 6, 14, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
+		{"weekly", time.Date(2023, 6, 18, 0, 0, 0, 0, time.UTC)},
+		{"6h", time.Date(2023, 6, 14, 12, 0, 0, 0, time.UTC)},
+		{"45m", time.Date(2023, 6, 14, 11, 15, 0, 0, time.UTC)},
+		{"7h", time.Date(2023, 6, 14, 14, 0, 0, 0, time.UTC)},
+		{"24h", time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2023, 6, 15, 2, 30, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2023, 6, 14, 10, 40, 0, 0, time.UTC)},
+		{"0 9-17 * * 1-5", time.Date(2023, 6, 14, 11, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2023, 6, 18, 0, 0, 0, 0, time.UTC)},
+		// day of month and day of week are OR-ed when both are restricted.
+		{"0 0 20 * 5", time.Date(2023, 6, 16, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		s, err := parseSchedule(test.spec)
+		assert.Nil(t, err, test.spec)
+		assert.Equal(t, test.want, s.next(now), test.spec)
+	}
+}
+
+func TestSchedule_IntervalMidnight(t *testing.T) {
+	s, err := parseSchedule("7h")
+	assert.Nil(t, err)
+
+	// the interval that would end after midnight is cut short.
+	now := time.Date(2023, 6, 14, 22, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC), s.next(now))
+	now = time.Date(2023, 6, 15, 1, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2023, 6, 15, 7, 0, 0, 0, time.UTC), s.next(now))
+}
+
+func TestSchedule_IntervalDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	s, err := parseSchedule("6h")
+	assert.Nil(t, err)
+
+	// clocks go forward at 2am on 2023-03-12 and back at 2am on 2023-11-05,
+	// and boundaries stay at the same wall-clock times.
+	now := time.Date(2023, 3, 12, 1, 0, 0, 0, loc)
+	assert.Equal(t, time.Date(2023, 3, 12, 6, 0, 0, 0, loc), s.next(now))
+	now = time.Date(2023, 11, 5, 1, 0, 0, 0, loc)
+	assert.Equal(t, time.Date(2023, 11, 5, 6, 0, 0, 0, loc), s.next(now))
+}
+
+func TestSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"sometimes",
+		"-1h",
+		"48h",
+		"0 * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"0 0 0 * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+	} {
+		_, err := parseSchedule(spec)
+		assert.NotNil(t, err, spec)
+	}
+
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+	l := &Logger{
+		Filename: logFile(dir),
+		Schedule: "sometimes",
+	}
+	defer l.Close()
+	n, err := l.Write([]byte("boo!"))
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, n)
+	assert.NoFileExists(t, logFile(dir))
+}
+
+func TestSchedule_RotateOnWrite(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	defer setFakeTime(fakeTime())
+	setFakeTime(time.Date(2023, 6, 14, 10, 30, 0, 0, time.UTC))
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+		Schedule: "hourly",
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	// still within the same hour.
+	setFakeTime(fakeTime().Add(20 * time.Minute))
+	b2 := []byte("foo!")
+	n, err = l.Write(b2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b2), n)
+	fileCount(t, dir, 1)
+
+	// crossing the boundary rotates before writing.
+	setFakeTime(fakeTime().Add(20 * time.Minute))
+	b3 := []byte("baz!")
+	n, err = l.Write(b3)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b3), n)
+
+	fileContainsContent(t, backupFile(dir), append(b, b2...))
+	fileContainsContent(t, filename, b3)
+	fileCount(t, dir, 2)
+}
+
+func TestSchedule_RotateExistingOnOpen(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	defer setFakeTime(fakeTime())
+	setFakeTime(time.Date(2023, 6, 14, 10, 30, 0, 0, time.UTC))
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	data := []byte("yesterday")
+	err := os.WriteFile(filename, data, 0o644)
+	assert.Nil(t, err)
+	yesterday := fakeTime().Add(-24 * time.Hour)
+	err = os.Chtimes(filename, yesterday, yesterday)
+	assert.Nil(t, err)
+
+	l := &Logger{
+		Filename: filename,
+		Schedule: "daily",
+	}
+	defer l.Close()
+
+	b := []byte("today")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	fileContainsContent(t, backupFile(dir), data)
+	fileContainsContent(t, filename, b)
+	fileCount(t, dir, 2)
+}
+
+func TestSchedule_RotateInBackground(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	scheduleCheckInterval = 5 * time.Millisecond
+	defer resetMocks()
+	defer setFakeTime(fakeTime())
+	setFakeTime(time.Date(2023, 6, 14, 10, 30, 0, 0, time.UTC))
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename: filename,
+		Schedule: "hourly",
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	// nothing else is written, but the boundary passes.
+	setFakeTime(fakeTime().Add(time.Hour))
+
+	// we need to wait a little bit since the rotation happens on a different
+	// goroutine.
+	<-time.After(100 * time.Millisecond)
+
+	fileContainsContent(t, backupFile(dir), b)
+	fileContainsContent(t, filename, []byte{})
+	fileCount(t, dir, 2)
+
+	// an empty file is not rotated again at the next boundary.
+	setFakeTime(fakeTime().Add(time.Hour))
+	<-time.After(100 * time.Millisecond)
+	fileCount(t, dir, 2)
+}
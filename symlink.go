@@ -0,0 +1,59 @@
+package woodcutter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// linkSuffix is appended to the log file name to make the name of the
+// temporary link that replaces Filename when SymlinkCurrent is set.
+const linkSuffix = ".link"
+
+// checkSymlinkCurrent returns an error if SymlinkCurrent is combined with
+// settings that rename or share the current file.
+func (l *Logger) checkSymlinkCurrent(naming *backupNaming) error {
+	switch {
+	case !l.SymlinkCurrent:
+		return nil
+	case naming.numbered():
+		return errors.New("symlink to current file can't be used with numbered backups")
+	case l.CopyTruncate:
+		return errors.New("symlink to current file can't be used with copytruncate")
+	case l.CrossProcess:
+		return errors.New("symlink to current file can't be used across processes")
+	default:
+		return nil
+	}
+}
+
+// currentTarget returns the path of the file that Filename links to when
+// SymlinkCurrent is set, or an empty string if Filename is not a link.
+func (l *Logger) currentTarget() string {
+	if !l.SymlinkCurrent {
+		return ""
+	}
+	target, err := os.Readlink(l.filename())
+	if err != nil {
+		return ""
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(l.dir(), target)
+	}
+	return target
+}
+
+// linkCurrent atomically makes Filename a link to path.
+func (l *Logger) linkCurrent(path string) error {
+	tmp := l.filename() + linkSuffix
+	_ = os.Remove(tmp)
+	if err := os.Symlink(filepath.Base(path), tmp); err != nil {
+		return fmt.Errorf("can't link to new logfile: %w", err)
+	}
+	if err := os.Rename(tmp, l.filename()); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("can't link to new logfile: %w", err)
+	}
+	return nil
+}
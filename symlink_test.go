@@ -0,0 +1,150 @@
+package woodcutter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// linksTo checks that filename is a symbolic link to target.
+func linksTo(t *testing.T, filename, target string) {
+	t.Helper()
+	link, err := os.Readlink(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Base(target), link)
+}
+
+func TestSymlink_Rotate(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	rec := &eventRecorder{}
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:       filename,
+		SymlinkCurrent: true,
+		OnEvent:        rec.onEvent,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+	first := backupFile(dir)
+	linksTo(t, filename, first)
+	fileContainsContent(t, filename, b)
+
+	newFakeTime()
+	err = l.Rotate()
+	assert.Nil(t, err)
+	second := backupFile(dir)
+	linksTo(t, filename, second)
+	fileContainsContent(t, first, b)
+
+	b2 := []byte("foo!")
+	n, err = l.Write(b2)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b2), n)
+	fileContainsContent(t, second, b2)
+
+	// the two files and the link.
+	fileCount(t, dir, 3)
+
+	finished, ok := rec.find(EventRotationFinished)
+	assert.True(t, ok)
+	assert.Equal(t, first, finished.Backup)
+}
+
+func TestSymlink_Retention(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:       filename,
+		SymlinkCurrent: true,
+		MaxBackups:     1,
+		Compress:       true,
+	}
+	defer l.Close()
+
+	var files []string
+	for i := 0; i < 3; i++ {
+		newFakeTime()
+		err := l.Rotate()
+		assert.Nil(t, err)
+		files = append(files, backupFile(dir))
+	}
+
+	// we need to wait a little bit since the files get compressed on a
+	// different goroutine.
+	<-time.After(300 * time.Millisecond)
+
+	// the current file is neither compressed nor counted as a backup.
+	linksTo(t, filename, files[2])
+	assert.FileExists(t, files[2])
+	assert.FileExists(t, files[1]+compressSuffix)
+	assert.NoFileExists(t, files[0])
+	assert.NoFileExists(t, files[0]+compressSuffix)
+	fileCount(t, dir, 3)
+}
+
+func TestSymlink_ReplacePlainFile(t *testing.T) {
+	currentTime = fakeTime
+	var seq byte
+	newUUID = func() uuid.UUID {
+		seq++
+		return uuid.UUID{seq}
+	}
+	defer resetMocks()
+	dir := t.TempDir()
+
+	filename := logFile(dir)
+	data := []byte("plain")
+	err := os.WriteFile(filename, data, 0o644)
+	assert.Nil(t, err)
+
+	l := &Logger{
+		Filename:       filename,
+		SymlinkCurrent: true,
+	}
+	defer l.Close()
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	assert.Nil(t, err)
+	assert.Equal(t, len(b), n)
+
+	// the plain file was rotated out before the link was made.
+	prefix := filepath.Join(dir, "foobar-"+fakeTime().UTC().Format(backupTimeFormat))
+	linksTo(t, filename, prefix+"-01000000.log")
+	fileContainsContent(t, filename, b)
+	fileContainsContent(t, prefix+"-02000000.log", data)
+	fileCount(t, dir, 3)
+}
+
+func TestSymlink_Invalid(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+
+	for _, l := range []*Logger{
+		{SymlinkCurrent: true, NumberedBackups: true},
+		{SymlinkCurrent: true, CopyTruncate: true},
+		{SymlinkCurrent: true, CrossProcess: true},
+	} {
+		dir := t.TempDir()
+		l.Filename = logFile(dir)
+		n, err := l.Write([]byte("boo!"))
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, n)
+		assert.NoFileExists(t, logFile(dir))
+		l.Close()
+	}
+}
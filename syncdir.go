@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package woodcutter
+
+func syncDir(_ string) error {
+	return nil
+}
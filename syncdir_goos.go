@@ -0,0 +1,23 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package woodcutter
+
+import (
+	"fmt"
+	"os"
+)
+
+// syncDir flushes the directory entries of dir to disk, so that files created
+// or renamed in it survive a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("can't open log directory: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("can't sync log directory: %w", err)
+	}
+	return nil
+}
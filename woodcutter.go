@@ -8,14 +8,15 @@
 // Woodcutter plays well with any logging package that can write to an
 // io.Writer, including the standard library's log package.
 //
-// Woodcutter assumes that only one process is writing to the output files.
-// Using the same Woodcutter configuration from multiple processes on the same
-// machine will result in improper behavior.
+// Woodcutter assumes that only one process is writing to the output files,
+// unless CrossProcess is set.  Using the same Woodcutter configuration from
+// multiple processes on the same machine without it will result in improper
+// behavior.
 package woodcutter
 
 import (
-	"compress/gzip"
-	"errors"
+	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -23,6 +24,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -60,6 +62,11 @@ var _ io.WriteCloser = (*Logger)(nil)
 // `/var/log/foo/server.log`, a backup created at 6:30pm on Nov 11 2016 would
 // use the filename `/var/log/foo/server-2016-11-04T18-30-00.000.log`
 //
+// If NumberedBackups is set, backups are instead named like logrotate names
+// them, `/var/log/foo/server.log.1` being the most recent backup,
+// `/var/log/foo/server.log.2` the one before it, and so on.  BackupTemplate
+// allows any other naming scheme.
+//
 // # Cleaning Up Old Log Files
 //
 // Whenever a new logfile gets created, old log files may be deleted.  The most
@@ -69,7 +76,41 @@ var _ io.WriteCloser = (*Logger)(nil)
 // MaxBackups.  Note that the time encoded in the timestamp is the rotation
 // time, which may differ from the last time that file was written to.
 //
-// If MaxBackups and MaxAge are both 0, no old log files will be deleted.
+// If MaxTotalSize is set, the oldest files are also deleted until the
+// remaining ones, measured after compression, take up at most MaxTotalSize
+// megabytes of disk.
+//
+// If MaxBackups, MaxAge and MaxTotalSize are all 0, no old log files will be
+// deleted.
+//
+// Backups made by lumberjack, which woodcutter is forked from, are named
+// `name-timestamp.ext`, without the random suffix.  They are cleaned up along
+// with woodcutter's own backups, and can be renamed into the current naming
+// scheme by setting RenameLegacyBackups.
+//
+// # Scheduled Rotation
+//
+// If Schedule is set, the log file is also rotated on wall-clock boundaries,
+// such as every hour or every day at midnight, whichever of MaxSize and the
+// schedule triggers first.  The rotation happens on the first write after a
+// boundary, or from a background goroutine if nothing is written, so that idle
+// processes still cut their files on time.  Boundaries are computed in UTC, or
+// in local time if LocalTime is set.  An empty log file is not rotated.
+//
+// # Errors and Events
+//
+// Errors that happen in the background can be observed through OnError, and
+// rotations and housekeeping through OnEvent.  Both are called after the
+// Logger has released its lock, so they may write to the Logger, but they may
+// be called concurrently from different goroutines.
+//
+// # Rotation Policies
+//
+// The MaxSize check can be replaced by setting Policy to a RotationPolicy,
+// which is consulted before every write.  SizePolicy, AgePolicy and
+// LineCountPolicy cover the common cases and can be combined with AnyPolicy
+// and AllPolicy, for example to rotate at 50MB or every 6 hours, whichever
+// comes first.
 type Logger struct {
 	// Filename is the file to write logs to.  Backup log files will be retained
 	// in the same directory.  It uses <processname>-woodcutter.log in
@@ -92,22 +133,276 @@ type Logger struct {
 	// deleted.)
 	MaxBackups int `json:"maxbackups" yaml:"maxbackups"`
 
+	// MaxTotalSize is the maximum total size in megabytes of the old log files
+	// to retain, as they are on disk, i.e. compressed if Compress is set.  The
+	// oldest files are deleted until the rest fit.  The default is not to
+	// limit the total size (though MaxBackups and MaxAge may still cause
+	// files to get deleted.)
+	MaxTotalSize int `json:"maxtotalsize" yaml:"maxtotalsize"`
+
+	// TotalSizeIncludesCurrent determines if the size of the current log file
+	// counts towards MaxTotalSize.  The default is to only count old log
+	// files.
+	TotalSizeIncludesCurrent bool `json:"totalsizeincludescurrent" yaml:"totalsizeincludescurrent"`
+
+	// MinFreeSpace is the amount of free space in megabytes to keep on the
+	// filesystem holding the log files.  It is checked on every rotation and
+	// cleanup, deleting the oldest backups while there is less.  If that is
+	// not enough, writes are handled according to DiskFullMode until space is
+	// available again.  The default is not to check free space.
+	MinFreeSpace int `json:"minfreespace" yaml:"minfreespace"`
+
+	// DiskFullMode determines what Write does while there is less than
+	// MinFreeSpace available: "drop" discards the data, "error" returns
+	// ErrDiskFull and "stderr" writes the data to standard error instead.  It
+	// defaults to "drop".
+	DiskFullMode string `json:"diskfullmode" yaml:"diskfullmode"`
+
+	// OnError, if set, is called with the errors that happen in the
+	// background, such as failures to compress or remove old log files, or to
+	// rotate on Schedule.  The default is to ignore them.
+	OnError func(err error) `json:"-" yaml:"-"`
+
+	// OnEvent, if set, is called when the Logger rotates the log file, or
+	// compresses or removes a backup.  See Event for details.
+	OnEvent func(event Event) `json:"-" yaml:"-"`
+
+	// OnRotate, if set, is called in the background after each rotation with
+	// the backup the log file was moved to, once the backup has been
+	// compressed, for example to upload it.  Errors it returns are passed to
+	// OnError.  It may write to the Logger, but must not close it: Close waits
+	// for running hooks to return, and their writes fail with ErrClosing in
+	// the meantime.  See RotatedFile for details.
+	OnRotate func(file RotatedFile) error `json:"-" yaml:"-"`
+
+	// MaxConcurrentHooks is the maximum number of OnRotate calls running at
+	// the same time.  It defaults to 1.
+	MaxConcurrentHooks int `json:"maxconcurrenthooks" yaml:"maxconcurrenthooks"`
+
+	// BufferSize is the size in bytes of a buffer in front of the log file.
+	// When it is set, writes are buffered and reach the file when the buffer
+	// fills up, every FlushInterval, on Flush, and before the file is rotated
+	// or closed.  Rotation still accounts for buffered data.  The default is
+	// to write to the file directly.
+	BufferSize int `json:"buffersize" yaml:"buffersize"`
+
+	// FlushInterval is how often buffered data is flushed to the log file in
+	// the background, when BufferSize is set.  A negative value disables
+	// periodic flushes.  It defaults to one second.
+	FlushInterval time.Duration `json:"flushinterval" yaml:"flushinterval"`
+
+	// Durability determines when the log file is synced to disk, so that its
+	// contents survive a crash or power loss: "never", on every "write",
+	// every SyncBytes written ("bytes"), every SyncInterval ("interval"), or
+	// only on "rotate".  Unless it is "never", the log file is also synced
+	// before it is rotated, and the log directory after a rotation or
+	// compression so that renames are persisted.  It defaults to "never".
+	Durability string `json:"durability" yaml:"durability"`
+
+	// SyncBytes is the number of bytes written between syncs when Durability
+	// is "bytes".
+	SyncBytes int `json:"syncbytes" yaml:"syncbytes"`
+
+	// SyncInterval is the time between syncs when Durability is "interval".
+	SyncInterval time.Duration `json:"syncinterval" yaml:"syncinterval"`
+
+	// OversizeMode determines what happens to a write larger than MaxSize:
+	// "error" rejects it, "dedicated" writes it to a file of its own, "split"
+	// spreads it over consecutive files of MaxSize, and "truncate" writes
+	// its first MaxSize bytes, ending with a marker.  Except with "error",
+	// EventOversizeWrite is sent.  It does not apply when Policy is set.  It
+	// defaults to "error".
+	OversizeMode string `json:"oversizemode" yaml:"oversizemode"`
+
+	// WholeRecords determines if rotation respects record boundaries, for
+	// callers that may write partial records, such as io.Copy from a pipe.
+	// Data after the last RecordDelimiter of a write is held back until the
+	// record is complete, so that a record is never split between two files.
+	// The pending record is written as is when it grows larger than
+	// MaxRecordSize, and when the Logger is closed.  The default is to
+	// rotate before any write.
+	WholeRecords bool `json:"wholerecords" yaml:"wholerecords"`
+
+	// RecordDelimiter separates records when WholeRecords is set.  It
+	// defaults to a newline.
+	RecordDelimiter string `json:"recorddelimiter" yaml:"recorddelimiter"`
+
+	// MaxRecordSize is the maximum size in bytes of a pending record when
+	// WholeRecords is set.  It defaults to 64 kilobytes.
+	MaxRecordSize int `json:"maxrecordsize" yaml:"maxrecordsize"`
+
+	// CrossProcess determines if several processes may write to the same log
+	// file.  Rotation and the removal and compression of backups are then
+	// coordinated with an advisory lock on the file named like the log file
+	// with a ".lock" suffix, and each process reopens the log file when
+	// another one has rotated it.  It is only supported on Linux and macOS.
+	// The default is to assume a single process.
+	CrossProcess bool `json:"crossprocess" yaml:"crossprocess"`
+
+	// ReopenInterval is how often the Logger checks in the background whether
+	// the log file has been moved or deleted, by logrotate or an operator for
+	// example, and reopens it if so instead of writing to the orphaned file.
+	// The default is not to check, but see Reopen.
+	ReopenInterval time.Duration `json:"reopeninterval" yaml:"reopeninterval"`
+
+	// CopyTruncate determines if the log file is rotated by copying it to the
+	// backup and truncating it in place, instead of renaming it, for when
+	// other processes hold the log file open, such as a redirected stdout.
+	// Data written by them between the copy and the truncation is lost.  The
+	// default is to rename the log file.
+	CopyTruncate bool `json:"copytruncate" yaml:"copytruncate"`
+
+	// SymlinkCurrent determines if the current log file is given a unique
+	// name from BackupTemplate when it is created, and Filename is a
+	// symbolic link to it that is switched atomically on each rotation, like
+	// rotatelogs -L does.  The current file keeps its name once rotated, and
+	// is left alone by MaxBackups, MaxAge and Compress until then.  It can't be
+	// combined with numbered backups, CopyTruncate or CrossProcess.  The
+	// default is to write to Filename itself.
+	SymlinkCurrent bool `json:"symlinkcurrent" yaml:"symlinkcurrent"`
+
+	// ArchiveDir is the directory backups are moved to after rotation, which
+	// may be on another filesystem.  Backups are made in the log file's
+	// directory first, then moved by the background goroutine, and MaxBackups,
+	// MaxAge, Compress and the other settings for backups apply to both
+	// directories.  The default is to keep backups next to the log file.
+	ArchiveDir string `json:"archivedir" yaml:"archivedir"`
+
+	// BackupDirLayout is a time layout, using the reference time of the time
+	// package, for subdirectories of the backup directory to put backups in by
+	// their rotation time, e.g. "2006/01/02" for one directory per day.  Like
+	// ArchiveDir, backups are moved there by the background goroutine, and
+	// subdirectories are removed once their backups are.  The default is to
+	// keep all backups in one directory.
+	BackupDirLayout string `json:"backupdirlayout" yaml:"backupdirlayout"`
+
+	// Index makes the background goroutine write an index next to each
+	// backup, before it is compressed, with the times of its first and last
+	// records and of records every IndexInterval bytes, for FindRange and
+	// Reader to skip to a time range.  Indexes are named after the
+	// uncompressed backup with a ".idx" suffix.
+	Index bool `json:"index" yaml:"index"`
+
+	// IndexTimeLayout is the layout, in the format of the time package, of
+	// the timestamp that starts each record.  The default is to use the time
+	// field of records written by log/slog, with the JSON or the text
+	// handler, or else an RFC 3339 timestamp at the start of the record.
+	// Records without a timestamp are left out of the index.
+	IndexTimeLayout string `json:"indextimelayout" yaml:"indextimelayout"`
+
+	// IndexInterval is the number of bytes between index entries.  The
+	// default is one megabyte.
+	IndexInterval int64 `json:"indexinterval" yaml:"indexinterval"`
+
 	// LocalTime determines if the time used for formatting the timestamps in
 	// backup files is the computer's local time.  The default is to use UTC
 	// time.
 	LocalTime bool `json:"localtime" yaml:"localtime"`
 
-	// Compress determines if the rotated log files should be compressed
-	// using gzip. The default is not to perform compression.
+	// Compress determines if the rotated log files should be compressed,
+	// using the algorithm selected by Compression. The default is not to
+	// perform compression.
 	Compress bool `json:"compress" yaml:"compress"`
 
+	// Compression selects the algorithm used when Compress is set: "gzip"
+	// (.gz), "zstd" (.zst) or "zlib" (.zz).  Backups compressed with any of
+	// them are recognized for MaxBackups and MaxAge, so it can be changed
+	// safely.  It defaults to "gzip".
+	Compression string `json:"compression" yaml:"compression"`
+
+	// CompressionLevel is the compression level, from 1 (fastest) to 9 (best)
+	// for gzip and zlib, or from 1 to 22 for zstd.  It defaults to the
+	// algorithm's default level.
+	CompressionLevel int `json:"compressionlevel" yaml:"compressionlevel"`
+
+	// NumberedBackups determines if backups are named like logrotate does,
+	// `name.1` being the most recent backup, `name.2` the one before and so
+	// on, instead of using a timestamp.  Existing backups are renamed to make
+	// room on every rotation, and their modification time is used for MaxAge.
+	// The default is to use timestamps.
+	NumberedBackups bool `json:"numberedbackups" yaml:"numberedbackups"`
+
+	// BackupTemplate determines how backups are named, overriding
+	// NumberedBackups.  It may contain the following placeholders:
+	//
+	//	{name}  the log file name without its extension
+	//	{ext}   the log file extension, including the dot
+	//	{time}  the rotation time, formatted with BackupTimeFormat
+	//	{seq}   the position of the backup, 1 being the most recent
+	//	{host}  the hostname
+	//	{pid}   the process id
+	//	{rand}  8 random hexadecimal characters
+	//
+	// It must contain {time} or {seq}, and backups are renamed on every
+	// rotation if it contains {seq}.  Only files whose name matches the
+	// template are considered backups for MaxBackups and MaxAge.  The default
+	// is "{name}-{time}-{rand}{ext}", or "{name}{ext}.{seq}" if
+	// NumberedBackups is set.
+	BackupTemplate string `json:"backuptemplate" yaml:"backuptemplate"`
+
+	// BackupTimeFormat is the time.Time layout used for {time} in
+	// BackupTemplate.  Layouts with numeric time zone offsets or path
+	// separators are not supported; see BackupDirLayout for directories.  It
+	// defaults to "2006-01-02T15-04-05.000".
+	BackupTimeFormat string `json:"backuptimeformat" yaml:"backuptimeformat"`
+
+	// RenameLegacyBackups determines if backups made by lumberjack, named
+	// `name-timestamp.ext` without a random suffix, are renamed into the
+	// BackupTemplate scheme.  Such backups are recognized for MaxBackups and
+	// MaxAge either way.  The default is to leave their names alone.
+	RenameLegacyBackups bool `json:"renamelegacybackups" yaml:"renamelegacybackups"`
+
+	// Policy decides when the log file is rotated, replacing the MaxSize
+	// check.  When it is set, writes larger than MaxSize are no longer
+	// rejected.  Schedule still applies independently.  The default is to
+	// rotate according to MaxSize.
+	Policy RotationPolicy `json:"-" yaml:"-"`
+
+	// Schedule determines the wall-clock boundaries on which the log file is
+	// rotated, in addition to MaxSize or Policy.  It is one of "hourly", "daily" or
+	// "weekly", an interval of at most 24h such as "6h" counted in wall-clock
+	// time from each midnight, with the last interval of the day cut short at
+	// midnight, or a cron expression of the form
+	// "minute hour day-of-month month day-of-week", such as "30 2 * * *".  The
+	// default is not to rotate on a schedule.
+	Schedule string `json:"schedule" yaml:"schedule"`
+
 	size int64
 	file *os.File
+	buf  *bufio.Writer
 	mu   sync.Mutex
 	wg   *sync.WaitGroup
 
 	millCh    chan bool
 	startMill sync.Once
+	millMu    sync.Mutex
+	prunable  map[string]bool
+	closing   bool
+
+	openedAt time.Time
+	writes   int64
+	lines    int64
+	unsynced int64
+	partial  []byte
+
+	sched      schedule
+	schedSpec  string
+	nextRotate time.Time
+
+	bgStop chan struct{}
+	bgDone chan struct{}
+
+	diskFull      atomic.Bool
+	lastDiskCheck time.Time
+
+	eventMu sync.Mutex
+	pending []notification
+
+	hookMu    sync.Mutex
+	hookQueue []RotatedFile
+	hookTaken []RotatedFile
+	hookSem   chan struct{}
+	hookWg    sync.WaitGroup
 }
 
 var (
@@ -128,62 +423,163 @@ var (
 	// to disk.
 	//nolint:gochecknoglobals // keep this global var for mocking in tests
 	megabyte = 1024 * 1024
+
+	// scheduleCheckInterval is the longest the background goroutine sleeps
+	// before checking the schedule again, so that changes to the wall clock
+	// are noticed.  It is a variable so tests can make it short.
+	//nolint:gochecknoglobals // keep this global var for mocking in tests
+	scheduleCheckInterval = time.Minute
+
+	// diskFreeSpace exists so it can be mocked out by tests.
+	//nolint:gochecknoglobals // keep this global var for mocking in tests
+	diskFreeSpace = diskFree
+
+	// sameFilesystem exists so it can be mocked out by tests.
+	//nolint:gochecknoglobals // keep this global var for mocking in tests
+	sameFilesystem = sameDevice
+
+	// diskCheckInterval is how often Write checks whether free space has
+	// recovered while the disk is full.
+	//nolint:gochecknoglobals // keep this global var for mocking in tests
+	diskCheckInterval = 5 * time.Second
+
+	// fileSync and dirSync exist so they can be mocked out by tests.
+	//nolint:gochecknoglobals // keep this global var for mocking in tests
+	fileSync = (*os.File).Sync
+	//nolint:gochecknoglobals // keep this global var for mocking in tests
+	dirSync = syncDir
 )
 
 // Write implements io.Writer.  If a write would cause the log file to be larger
 // than MaxSize, the file is closed, renamed to include a timestamp of the
 // current time, and a new log file is created using the original log file name.
-// If the length of the write is greater than MaxSize, an error is returned.
+// If the length of the write is greater than MaxSize, it is handled according
+// to OversizeMode, which by default returns an error.
+// When a Policy is set, it decides instead of MaxSize whether to rotate.  When
+// WholeRecords is set, incomplete records are held back until they are
+// complete, and rotation only happens between records.
 func (l *Logger) Write(p []byte) (int, error) {
+	defer l.deliver()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	writeLen := int64(len(p))
-	if writeLen > l.max() {
-		return 0, fmt.Errorf(
-			"write length %d exceeds maximum file size %d", writeLen, l.max(),
-		)
+	if l.closing {
+		return 0, ErrClosing
+	}
+
+	if l.WholeRecords {
+		return l.writeRecords(p)
+	}
+	return l.write(p)
+}
+
+// write writes p to the current file, rotating first if needed.
+func (l *Logger) write(p []byte) (int, error) {
+	if l.Policy == nil && int64(len(p)) > l.max() {
+		return l.writeOversize(p)
+	}
+	return l.writeFile(p)
+}
+
+// writeFile writes p to the current file regardless of its size, rotating
+// first if needed.
+func (l *Logger) writeFile(p []byte) (int, error) {
+	if l.checkDiskFull() {
+		return l.writeDiskFull(p)
 	}
 
 	if l.file == nil {
-		if err := l.openExistingOrNew(len(p)); err != nil {
+		if err := l.openExistingOrNew(p); err != nil {
+			return 0, err
+		}
+	}
+
+	if l.CrossProcess {
+		if err := l.reopenIfMoved(); err != nil {
+			return 0, err
+		}
+	} else if l.CopyTruncate {
+		if err := l.refreshSize(); err != nil {
 			return 0, err
 		}
 	}
 
-	if l.size+writeLen > l.max() {
-		if err := l.rotate(); err != nil {
+	if l.shouldRotate(l.size, p) || l.scheduleDue() {
+		if err := l.rotateDue(p); err != nil {
 			return 0, err
 		}
 	}
 
-	n, err := l.file.Write(p)
+	n, err := l.output().Write(p)
 	l.size += int64(n)
+	l.writes++
+	l.lines += int64(bytes.Count(p[:n], []byte{'\n'}))
+	if err == nil {
+		err = l.syncAfterWrite(n)
+	}
 
 	return n, err
 }
 
 // Close implements io.Closer, closes the current logfile,
-// and terminates the mill goroutine if it is running.
+// and terminates the mill and background goroutines if they are running.
+// Until the OnRotate hooks have returned, writes fail with ErrClosing.  The
+// Logger can be written to again afterwards, which reopens the log file.
 func (l *Logger) Close() error {
+	defer l.deliver()
+
+	// keep writes, the ones of rotate hooks included, from reopening the file
+	// and starting the goroutines again until the hooks are done.
 	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.closing = true
+	l.mu.Unlock()
+	defer func() {
+		l.hookWg.Wait()
+		l.mu.Lock()
+		l.closing = false
+		l.mu.Unlock()
+	}()
 
+	l.stopBackground()
+
+	l.mu.Lock()
+	err := l.writePartial()
+	var millWg *sync.WaitGroup
 	if l.millCh != nil {
 		close(l.millCh)
-		l.wg.Wait()
+		millWg = l.wg
 		l.millCh = nil
 	}
+	l.mu.Unlock()
+
+	// the mill delivers events, whose callbacks may write to the Logger, so
+	// wait for it without holding the lock.
+	if millWg != nil {
+		millWg.Wait()
+	}
 
-	return l.close()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if closeErr := l.close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
 
-// close closes the file if it is open.
+// close flushes, syncs if needed, and closes the file if it is open.
 func (l *Logger) close() error {
 	if l.file == nil {
 		return nil
 	}
-	err := l.file.Close()
+	var err error
+	if l.durable() {
+		err = l.sync()
+	} else {
+		err = l.flush()
+	}
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
 	l.file = nil
 	return err
 }
@@ -194,8 +590,12 @@ func (l *Logger) close() error {
 // SIGHUP.  After rotating, this initiates compression and removal of old log
 // files according to the configuration.
 func (l *Logger) Rotate() error {
+	defer l.deliver()
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	if l.closing {
+		return ErrClosing
+	}
 	return l.rotate()
 }
 
@@ -203,12 +603,31 @@ func (l *Logger) Rotate() error {
 // (if it exists), opens a new file with the original filename, and then runs
 // post-rotation processing and removal.
 func (l *Logger) rotate() error {
+	unlock, err := l.lockRotation()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return l.rotateFile()
+}
+
+// rotateFile rotates the log file like rotate, with the cross-process lock
+// already held.
+func (l *Logger) rotateFile() error {
 	if err := l.close(); err != nil {
 		return err
 	}
 	if err := l.openNew(); err != nil {
 		return err
 	}
+	if l.MinFreeSpace > 0 {
+		l.millMu.Lock()
+		err := l.ensureFreeSpace()
+		l.millMu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
 	l.mill()
 	return nil
 }
@@ -216,66 +635,179 @@ func (l *Logger) rotate() error {
 // openNew opens a new log file for writing, moving any old log file out of the
 // way.  This methods assumes the file has already been closed.
 func (l *Logger) openNew() error {
+	if err := l.loadSchedule(); err != nil {
+		return err
+	}
+
 	err := os.MkdirAll(l.dir(), 0o755)
 	if err != nil {
 		return fmt.Errorf("can't make directories for new logfile: %w", err)
 	}
 
 	name := l.filename()
+	path := name
+	if l.SymlinkCurrent {
+		naming, namingErr := l.backupNaming()
+		if namingErr != nil {
+			return namingErr
+		}
+		if checkErr := l.checkSymlinkCurrent(naming); checkErr != nil {
+			return checkErr
+		}
+
+		// keep the mill from taking the new file for a backup until Filename
+		// links to it.
+		l.millMu.Lock()
+		defer l.millMu.Unlock()
+
+		// the current file gets a unique name, which it keeps once rotated.
+		if path, err = l.nextBackupName(name); err != nil {
+			return err
+		}
+	}
+
 	const permissions = 0o600
 	mode := os.FileMode(permissions)
+	var (
+		rotated *Event
+		start   time.Time
+	)
 	info, err := osStat(name)
 	if err == nil {
+		start = time.Now()
+		l.notify(Event{Kind: EventRotationStarted, Filename: name, Size: info.Size()})
+
 		// Copy the mode off the old logfile.
 		mode = info.Mode()
-		// move the existing file
-		newname := backupName(name, l.LocalTime)
-		if renameErr := os.Rename(name, newname); renameErr != nil {
-			return fmt.Errorf("can't rename log file: %w", renameErr)
+
+		var newname string
+		if target := l.currentTarget(); target != "" {
+			// the file Filename links to already has its backup name.
+			newname = target
+		} else {
+			// move the existing file
+			var nameErr error
+			newname, nameErr = l.nextBackupName(name)
+			if nameErr != nil {
+				return nameErr
+			}
+			if l.CopyTruncate {
+				if copyErr := copyTruncate(name, newname, info, l.durable()); copyErr != nil {
+					return copyErr
+				}
+			} else if renameErr := os.Rename(name, newname); renameErr != nil {
+				return fmt.Errorf("can't rename log file: %w", renameErr)
+			}
 		}
 
 		// this is a no-op anywhere but linux
-		if chownErr := chown(name, info); chownErr != nil {
-			return chownErr
+		if !l.CopyTruncate {
+			if chownErr := chown(path, info); chownErr != nil {
+				return chownErr
+			}
+		}
+		rotated = &Event{
+			Kind:     EventRotationFinished,
+			Filename: name,
+			Backup:   newname,
+			Size:     info.Size(),
 		}
+		if backupInfo, statErr := osStat(newname); statErr == nil {
+			rotated.backupInfo = backupInfo
+		}
+		l.queueRotated(RotatedFile{Filename: name, Backup: newname, Time: currentTime()})
 	}
 
 	// we use truncate here because this should only get called when we've moved
 	// the file ourselves. if someone else creates the file in the meantime,
 	// just wipe out the contents.
-	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if l.CrossProcess || l.CopyTruncate {
+		// other processes may hold the file open, or already have created it
+		// and written to it.
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, mode)
 	if err != nil {
 		return fmt.Errorf("can't open new logfile: %w", err)
 	}
-	l.file = f
+	if l.SymlinkCurrent {
+		if err := l.linkCurrent(path); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	l.setFile(f)
 	l.size = 0
+	l.resetCounters()
+	l.scheduleFrom(currentTime())
+	l.report(l.syncLogDir(l.dir()))
+
+	if rotated != nil {
+		rotated.Duration = time.Since(start)
+		l.notify(*rotated)
+	}
 	return nil
 }
 
-// backupName creates a new filename from the given name, inserting a timestamp
-// between the filename and the extension, using the local time if requested
-// (otherwise UTC).
-func backupName(name string, local bool) string {
-	dir := filepath.Dir(name)
-	filename := filepath.Base(name)
-	ext := filepath.Ext(filename)
-	prefix := filename[:len(filename)-len(ext)]
+// nextBackupName returns the name to move the current log file to when it is
+// rotated, first making room for it if backups are numbered.
+func (l *Logger) nextBackupName(name string) (string, error) {
+	naming, err := l.backupNaming()
+	if err != nil {
+		return "", err
+	}
+
+	seq := 0
+	if naming.numbered() {
+		if err := l.shiftBackups(naming); err != nil {
+			return "", err
+		}
+		seq = 1
+	}
+
 	t := currentTime()
-	if !local {
+	if !l.LocalTime {
 		t = t.UTC()
 	}
-
-	timestamp := t.Format(backupTimeFormat)
-	randomSuffix := newUUID().String()[:randomSuffixLen] // first 4 bytes of UUID
-	return filepath.Join(dir, fmt.Sprintf("%s-%s-%s%s", prefix, timestamp, randomSuffix, ext))
+	return filepath.Join(filepath.Dir(name), naming.format(t, seq)), nil
 }
 
-// openExistingOrNew opens the logfile if it exists and if the current write
-// would not put it over MaxSize.  If there is no such file or the write would
-// put it over the MaxSize, a new file is created.
-func (l *Logger) openExistingOrNew(writeLen int) error {
+// openExistingOrNew opens the logfile if it exists and if the rotation policy
+// allows writing p to it.  If there is no such file or the policy asks for a
+// rotation, a new file is created.
+func (l *Logger) openExistingOrNew(p []byte) error {
 	l.mill()
 
+	if err := l.loadSchedule(); err != nil {
+		return err
+	}
+	naming, err := l.backupNaming()
+	if err != nil {
+		return err
+	}
+	if err := l.checkSymlinkCurrent(naming); err != nil {
+		return err
+	}
+	if err := l.checkDiskFullMode(); err != nil {
+		return err
+	}
+	if err := l.checkDurability(); err != nil {
+		return err
+	}
+	if err := l.checkOversizeMode(); err != nil {
+		return err
+	}
+	if _, err := l.partitionLayout(); err != nil {
+		return err
+	}
+	if _, err := l.compressor(); err != nil {
+		return err
+	}
+	if l.CrossProcess {
+		return l.openAppend()
+	}
+
 	filename := l.filename()
 	info, err := osStat(filename)
 	if os.IsNotExist(err) {
@@ -284,8 +816,13 @@ func (l *Logger) openExistingOrNew(writeLen int) error {
 	if err != nil {
 		return fmt.Errorf("error getting log file info: %w", err)
 	}
+	if l.SymlinkCurrent && l.currentTarget() == "" {
+		// move a plain log file aside so that Filename can become a link.
+		return l.rotate()
+	}
 
-	if info.Size()+int64(writeLen) >= l.max() {
+	l.resetCounters()
+	if l.shouldRotate(info.Size(), p) || l.scheduleDueSince(info.ModTime()) {
 		return l.rotate()
 	}
 
@@ -295,11 +832,21 @@ func (l *Logger) openExistingOrNew(writeLen int) error {
 		// it and open a new log file.
 		return l.openNew()
 	}
-	l.file = file
+	l.setFile(file)
 	l.size = info.Size()
+	l.scheduleFrom(info.ModTime())
 	return nil
 }
 
+// resetCounters resets the statistics kept about the current file for the
+// rotation policy.
+func (l *Logger) resetCounters() {
+	l.openedAt = currentTime()
+	l.writes = 0
+	l.lines = 0
+	l.unsynced = 0
+}
+
 // filename generates the name of the logfile from the current time.
 func (l *Logger) filename() string {
 	if l.Filename != "" {
@@ -314,10 +861,38 @@ func (l *Logger) filename() string {
 // files are removed, keeping at most l.MaxBackups files, as long as
 // none of them are older than MaxAge.
 func (l *Logger) millRunOnce() error {
-	if l.MaxBackups == 0 && l.MaxAge == 0 && !l.Compress {
+	unlock, err := l.lockRotation()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	// backups rotated from now on are handled by the next run.
+	defer l.runHooks(l.takeRotated())
+
+	if l.MaxBackups == 0 && l.MaxAge == 0 && l.MaxTotalSize == 0 && l.MinFreeSpace == 0 &&
+		!l.Compress && !l.RenameLegacyBackups && l.ArchiveDir == "" && l.BackupDirLayout == "" &&
+		!l.Index {
 		return nil
 	}
 
+	if l.RenameLegacyBackups {
+		naming, err := l.backupNaming()
+		if err != nil {
+			return err
+		}
+		if err := l.renameLegacyBackups(naming); err != nil {
+			return err
+		}
+	}
+
+	if err := l.archiveBackups(); err != nil {
+		return err
+	}
+
 	files, err := l.oldLogFiles()
 	if err != nil {
 		return err
@@ -328,29 +903,92 @@ func (l *Logger) millRunOnce() error {
 	var compress []logInfo
 	if l.Compress {
 		for _, f := range files {
-			if !strings.HasSuffix(f.Name(), compressSuffix) {
+			if compressionSuffix(f.Name()) == "" {
 				compress = append(compress, f)
 			}
 		}
 	}
 
 	for _, f := range remove {
-		errRemove := os.Remove(filepath.Join(l.dir(), f.Name()))
+		errRemove := l.removeBackup(f)
 		if err == nil && errRemove != nil {
 			err = errRemove
 		}
 	}
+	if errIndex := l.indexBackups(files); err == nil && errIndex != nil {
+		err = errIndex
+	}
 	for _, f := range compress {
-		fn := filepath.Join(l.dir(), f.Name())
-		errCompress := compressLogFile(fn, fn+compressSuffix)
+		errCompress := l.compressBackup(f)
 		if err == nil && errCompress != nil {
 			err = errCompress
 		}
 	}
 
+	if l.MaxTotalSize > 0 {
+		errBudget := l.removeOverBudget()
+		if err == nil && errBudget != nil {
+			err = errBudget
+		}
+	}
+
+	if errSpace := l.ensureFreeSpace(); err == nil && errSpace != nil {
+		err = errSpace
+	}
+
+	if errPrune := l.pruneBackupDirs(); err == nil && errPrune != nil {
+		err = errPrune
+	}
+
+	return err
+}
+
+// removeOverBudget removes the oldest backups until the backups, and the
+// current log file if TotalSizeIncludesCurrent is set, fit in MaxTotalSize.
+// It runs after compression so that compressed backups are counted at their
+// compressed size.
+func (l *Logger) removeOverBudget() error {
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return err
+	}
+
+	budget := int64(l.MaxTotalSize) * int64(megabyte)
+	if l.TotalSizeIncludesCurrent {
+		if info, statErr := osStat(l.filename()); statErr == nil {
+			budget -= info.Size()
+		}
+	}
+
+	for _, f := range filesOverBudget(files, budget) {
+		errRemove := l.removeBackup(f)
+		if err == nil && errRemove != nil {
+			err = errRemove
+		}
+	}
 	return err
 }
 
+// filesOverBudget returns the files, sorted newest first, that don't fit in
+// budget bytes once all the newer ones have been kept.  A backup that exists
+// both compressed and uncompressed counts twice, since it uses the disk space
+// of both.
+func filesOverBudget(files []logInfo, budget int64) []logInfo {
+	var total int64
+	for i, f := range files {
+		info, err := f.Info()
+		if err != nil {
+			// it's already gone.
+			continue
+		}
+		total += info.Size()
+		if total > budget {
+			return files[i:]
+		}
+	}
+	return nil
+}
+
 // filesToRemoveAndKeep returns a list of `logInfo` of files to preserve
 // and a list of `logInfo` of files to remove based on
 // the max number of backups and the max age configured
@@ -361,10 +999,11 @@ func (l *Logger) filesToRemoveAndKeep(oldLogFiles []logInfo) ([]logInfo, []logIn
 	filesToKeep := oldLogFiles
 	if l.MaxBackups > 0 && l.MaxBackups < len(oldLogFiles) {
 		preserved := make(map[string]struct{})
+		filesToKeep = nil
 		for _, f := range oldLogFiles {
 			// Only count the uncompressed log file or the
 			// compressed log file, not both.
-			fn := strings.TrimSuffix(f.Name(), compressSuffix)
+			fn := strings.TrimSuffix(f.Name(), compressionSuffix(f.Name()))
 			preserved[fn] = struct{}{}
 
 			if len(preserved) > l.MaxBackups {
@@ -395,12 +1034,12 @@ func (l *Logger) filesToRemoveAndKeep(oldLogFiles []logInfo) ([]logInfo, []logIn
 }
 
 // millRun runs in a goroutine to manage post-rotation compression and removal
-// of old log files.
-func (l *Logger) millRun() {
+// of old log files, until millCh is closed.
+func (l *Logger) millRun(millCh <-chan bool) {
 	defer l.wg.Done()
-	for range l.millCh {
-		// what am I going to do, log this?
-		_ = l.millRunOnce()
+	for range millCh {
+		l.report(l.millRunOnce())
+		l.deliver()
 	}
 }
 
@@ -411,7 +1050,7 @@ func (l *Logger) mill() {
 		l.wg = new(sync.WaitGroup)
 		l.wg.Add(1)
 		l.millCh = make(chan bool, 1)
-		go l.millRun()
+		go l.millRun(l.millCh)
 	})
 	select {
 	case l.millCh <- true:
@@ -419,50 +1058,203 @@ func (l *Logger) mill() {
 	}
 }
 
+// startBackground starts the goroutine that performs time-driven work, such as
+// scheduled rotation, if it is not already running.
+func (l *Logger) startBackground() {
+	if l.bgStop != nil || l.closing {
+		return
+	}
+	l.bgStop = make(chan struct{})
+	l.bgDone = make(chan struct{})
+	go l.backgroundRun(l.bgStop, l.bgDone)
+}
+
+// stopBackground terminates the background goroutine and waits for it to exit.
+// It must be called without holding l.mu since the goroutine acquires it.
+func (l *Logger) stopBackground() {
+	l.mu.Lock()
+	stop, done := l.bgStop, l.bgDone
+	l.bgStop, l.bgDone = nil, nil
+	l.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// backgroundRun runs in a goroutine to do time-driven work: it periodically
+// flushes and syncs data and checks whether the log file was moved, and
+// rotates the log file when a schedule boundary passes, even if nothing is
+// being written.
+func (l *Logger) backgroundRun(stop, done chan struct{}) {
+	defer close(done)
+	for {
+		l.mu.Lock()
+		wait := l.backgroundWait()
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		l.mu.Lock()
+		select {
+		case <-stop:
+			l.mu.Unlock()
+			return
+		default:
+		}
+		l.report(l.flush())
+		if l.syncDue() {
+			l.report(l.sync())
+		}
+		if l.ReopenInterval > 0 && l.file != nil {
+			l.report(l.reopenIfMoved())
+		}
+		if l.file != nil && l.scheduleDue() {
+			if l.size == 0 {
+				// nothing to rotate, just wait for the next boundary.
+				l.scheduleFrom(currentTime())
+			} else {
+				l.report(l.rotateDue(nil))
+			}
+		}
+		l.mu.Unlock()
+		l.deliver()
+	}
+}
+
+// backgroundWait returns how long the background goroutine should sleep
+// before its next check.
+func (l *Logger) backgroundWait() time.Duration {
+	wait := scheduleCheckInterval
+	if l.file != nil && !l.nextRotate.IsZero() {
+		if until := l.nextRotate.Sub(currentTime()); until < wait {
+			wait = until
+		}
+	}
+	if interval := l.flushInterval(); l.file != nil && interval > 0 && interval < wait {
+		wait = interval
+	}
+	if l.Durability == DurabilityInterval && l.file != nil && l.SyncInterval < wait {
+		wait = l.SyncInterval
+	}
+	if l.ReopenInterval > 0 && l.file != nil && l.ReopenInterval < wait {
+		wait = l.ReopenInterval
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
 // oldLogFiles returns the list of backup log files stored in the same
-// directory as the current log file, sorted by ModTime.
+// directory as the current log file or in ArchiveDir, including their
+// BackupDirLayout subdirectories, sorted by ModTime.
 func (l *Logger) oldLogFiles() ([]logInfo, error) {
-	files, err := os.ReadDir(l.dir())
+	naming, err := l.backupNaming()
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := l.legacyNaming(naming)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := l.partitionPatterns()
 	if err != nil {
-		return nil, fmt.Errorf("can't read log file directory: %w", err)
+		return nil, err
 	}
+
 	logFiles := []logInfo{}
+	for _, dir := range l.backupDirs() {
+		files, err := l.backupsUnder(dir, levels, naming, legacy)
+		if os.IsNotExist(err) && dir != l.dir() {
+			// nothing has been archived yet.
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't read log file directory: %w", err)
+		}
+		logFiles = append(logFiles, files...)
+	}
+
+	sort.Sort(byFormatTime(logFiles))
 
-	prefix, ext := l.prefixAndExt()
+	return logFiles, nil
+}
 
+// backupsIn returns the backups among the given files of dir, recognizing
+// backups named according to naming, or to legacy if not nil.
+func (l *Logger) backupsIn(dir string, files []os.DirEntry, naming, legacy *backupNaming) []logInfo {
+	// with SymlinkCurrent, the current file is named like a backup.
+	current := ""
+	if dir == l.dir() {
+		current = filepath.Base(l.currentTarget())
+	}
+
+	var logFiles []logInfo
 	for _, f := range files {
-		if f.IsDir() {
+		if f.IsDir() || f.Name() == current {
 			continue
 		}
-		if t, extractErr := l.timeFromName(f.Name(), prefix, ext); extractErr == nil {
-			logFiles = append(logFiles, logInfo{t, f})
-			continue
+		fields, _, parseErr := naming.parseFile(f.Name())
+		isLegacy := false
+		if parseErr != nil && legacy != nil {
+			// it may still be a backup made by lumberjack.
+			fields, _, parseErr = legacy.parseFile(f.Name())
+			isLegacy = parseErr == nil
 		}
-		if t, extractErr := l.timeFromName(f.Name(), prefix, ext+compressSuffix); extractErr == nil {
-			logFiles = append(logFiles, logInfo{t, f})
+		if parseErr != nil {
+			// error parsing means that the name was not generated by
+			// woodcutter, and therefore it's not a backup file.
 			continue
 		}
-		// error parsing means that the suffix at the end was not generated
-		// by woodcutter, and therefore it's not a backup file.
+		if fields.timestamp.IsZero() {
+			// without a timestamp in the name, fall back to the time the
+			// backup was last written to.
+			info, infoErr := f.Info()
+			if infoErr != nil {
+				continue
+			}
+			fields.timestamp = info.ModTime()
+			if !l.LocalTime {
+				fields.timestamp = fields.timestamp.UTC()
+			}
+		}
+		logFiles = append(logFiles, logInfo{
+			timestamp: fields.timestamp,
+			seq:       fields.seq,
+			legacy:    isLegacy,
+			dir:       dir,
+			DirEntry:  f,
+		})
 	}
-
-	sort.Sort(byFormatTime(logFiles))
-
-	return logFiles, nil
+	return logFiles
 }
 
-// timeFromName extracts the formatted time from the filename by stripping off
-// the filename's prefix and extension. This prevents someone's filename from
-// confusing time.parse.
-func (l *Logger) timeFromName(filename, prefix, ext string) (time.Time, error) {
-	if !strings.HasPrefix(filename, prefix) {
-		return time.Time{}, errors.New("mismatched prefix")
+// timeFromName extracts the formatted time from the filename according to the
+// backup template, or the lumberjack naming scheme. This prevents someone's
+// filename from confusing time.parse.
+func (l *Logger) timeFromName(filename string) (time.Time, error) {
+	naming, err := l.backupNaming()
+	if err != nil {
+		return time.Time{}, err
 	}
-	if !strings.HasSuffix(filename, ext) {
-		return time.Time{}, errors.New("mismatched extension")
+	fields, err := naming.parse(filename)
+	if err != nil {
+		legacy, legacyErr := l.legacyNaming(naming)
+		if legacyErr != nil || legacy == nil {
+			return time.Time{}, err
+		}
+		fields, err = legacy.parse(filename)
 	}
-	ts := filename[len(prefix) : len(filename)-len(ext)-randomSuffixLen-1] // final 1 for hyphen
-	return time.Parse(backupTimeFormat, ts)
+	return fields.timestamp, err
 }
 
 // max returns the maximum size in bytes of log files before rolling.
@@ -478,18 +1270,10 @@ func (l *Logger) dir() string {
 	return filepath.Dir(l.filename())
 }
 
-// prefixAndExt returns the filename part and extension part from the Logger's
-// filename.
-func (l *Logger) prefixAndExt() (string, string) {
-	filename := filepath.Base(l.filename())
-	ext := filepath.Ext(filename)
-	prefix := filename[:len(filename)-len(ext)] + "-"
-	return prefix, ext
-}
-
 // compressLogFile compresses the given log file, removing the
-// uncompressed log file if successful.
-func compressLogFile(src, dst string) (err error) {
+// uncompressed log file if successful.  If durable is set, the compressed file
+// is synced to disk before the uncompressed one is removed.
+func compressLogFile(src, dst string, c compressor, durable bool) (err error) {
 	f, err := os.Open(src)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %w", err)
@@ -513,8 +1297,6 @@ func compressLogFile(src, dst string) (err error) {
 	}
 	defer gzf.Close()
 
-	gz := gzip.NewWriter(gzf)
-
 	defer func() {
 		if err != nil {
 			os.Remove(dst)
@@ -522,12 +1304,21 @@ func compressLogFile(src, dst string) (err error) {
 		}
 	}()
 
+	gz, err := c.newWriter(gzf)
+	if err != nil {
+		return err
+	}
 	if _, err = io.Copy(gz, f); err != nil {
 		return err
 	}
 	if err = gz.Close(); err != nil {
 		return err
 	}
+	if durable {
+		if err = fileSync(gzf); err != nil {
+			return err
+		}
+	}
 	if err = gzf.Close(); err != nil {
 		return err
 	}
@@ -539,16 +1330,32 @@ func compressLogFile(src, dst string) (err error) {
 }
 
 // logInfo is a convenience struct to return the filename and its embedded
-// timestamp.
+// timestamp, or its sequence number for numbered backups.
 type logInfo struct {
 	timestamp time.Time
+	seq       int
+	legacy    bool
+	dir       string
 	os.DirEntry
 }
 
-// byFormatTime sorts by newest time formatted in the name.
+// path returns the path of the backup.
+func (f logInfo) path() string {
+	return filepath.Join(f.dir, f.Name())
+}
+
+// byFormatTime sorts by newest time formatted in the name, or by lowest
+// sequence number for numbered backups, which are newer than any backup
+// without a sequence number.
 type byFormatTime []logInfo
 
 func (b byFormatTime) Less(i, j int) bool {
+	if (b[i].seq > 0) != (b[j].seq > 0) {
+		return b[i].seq > 0
+	}
+	if b[i].seq > 0 {
+		return b[i].seq < b[j].seq
+	}
 	return b[i].timestamp.After(b[j].timestamp)
 }
 
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime/pprof"
+	"sync"
 	"testing"
 	"time"
 
@@ -26,12 +27,25 @@ import (
 // that doesn't change unless we want it to. The same goes for random UUID.
 //
 //nolint:gochecknoglobals // need global time as we need to mock it across all tests
-var fakeCurrentTime = time.Now()
+var (
+	fakeCurrentTime = time.Now()
+	fakeTimeMu      sync.Mutex
+)
 
 func fakeTime() time.Time {
+	fakeTimeMu.Lock()
+	defer fakeTimeMu.Unlock()
 	return fakeCurrentTime
 }
 
+// setFakeTime sets the fake "current time", which the mill and background
+// goroutines may be reading.
+func setFakeTime(t time.Time) {
+	fakeTimeMu.Lock()
+	defer fakeTimeMu.Unlock()
+	fakeCurrentTime = t
+}
+
 //nolint:gochecknoglobals // need global random UUID as we need to mock it across all tests
 var fakeRandomUUID = uuid.New()
 
@@ -460,6 +474,54 @@ func TestMain_MaxAge(t *testing.T) {
 	fileContainsContent(t, backupFile(dir), b2)
 }
 
+func TestMain_MaxTotalSize(t *testing.T) {
+	currentTime = fakeTime
+	newUUID = fakeUUID
+	megabyte = 1
+
+	dir := t.TempDir()
+
+	// make 3 backup files of different sizes, one of them compressed.
+	oldest := backupFile(dir)
+	err := os.WriteFile(oldest, []byte("oldest backup"), 0o644)
+	assert.Nil(t, err)
+
+	newFakeTime()
+	older := backupFile(dir) + compressSuffix
+	err = os.WriteFile(older, []byte("older"), 0o644)
+	assert.Nil(t, err)
+
+	newFakeTime()
+	newer := backupFile(dir)
+	err = os.WriteFile(newer, []byte("newer"), 0o644)
+	assert.Nil(t, err)
+
+	filename := logFile(dir)
+	err = os.WriteFile(filename, []byte("current"), 0o644)
+	assert.Nil(t, err)
+
+	l := &Logger{
+		Filename:     filename,
+		MaxTotalSize: 12,
+	}
+	err = l.millRunOnce()
+	assert.Nil(t, err)
+
+	// the two newest backups fit in 12 bytes, the oldest one doesn't.
+	assert.NoFileExists(t, oldest)
+	assert.FileExists(t, older)
+	assert.FileExists(t, newer)
+
+	// counting the current file leaves room for the newest backup only.
+	l.TotalSizeIncludesCurrent = true
+	err = l.millRunOnce()
+	assert.Nil(t, err)
+
+	assert.NoFileExists(t, older)
+	assert.FileExists(t, newer)
+	assert.FileExists(t, filename)
+}
+
 func TestMain_OldLogFiles(t *testing.T) {
 	currentTime = fakeTime
 	newUUID = fakeUUID
@@ -502,7 +564,6 @@ func TestMain_OldLogFiles(t *testing.T) {
 
 func TestMain_TimeFromName(t *testing.T) {
 	l := &Logger{Filename: "/var/log/myfoo/foo.log"}
-	prefix, ext := l.prefixAndExt()
 
 	tests := []struct {
 		filename string
@@ -516,7 +577,7 @@ func TestMain_TimeFromName(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		got, err := l.timeFromName(test.filename, prefix, ext)
+		got, err := l.timeFromName(test.filename)
 		assert.Equal(t, test.want, got)
 		assert.Equal(t, err != nil, test.wantErr)
 	}
@@ -715,7 +776,8 @@ func TestMain_Json(t *testing.T) {
 	"maxage": 10,
 	"maxbackups": 3,
 	"localtime": true,
-	"compress": true
+	"compress": true,
+	"schedule": "daily"
 }`[1:])
 
 	l := Logger{}
@@ -727,6 +789,7 @@ func TestMain_Json(t *testing.T) {
 	assert.Equal(t, 3, l.MaxBackups)
 	assert.Equal(t, true, l.LocalTime)
 	assert.Equal(t, true, l.Compress)
+	assert.Equal(t, "daily", l.Schedule)
 }
 
 func TestMain_MillGoRoutineLeak(t *testing.T) {
@@ -788,7 +851,7 @@ func fileCount(t *testing.T, dir string, expectedCount int) {
 
 // newFakeTime sets the fake "current time" to two days later.
 func newFakeTime() {
-	fakeCurrentTime = fakeCurrentTime.Add(time.Hour * 24 * 2)
+	setFakeTime(fakeTime().Add(time.Hour * 24 * 2))
 }
 
 // resetMocks resets mocks set in the above tests.
@@ -797,6 +860,12 @@ func resetMocks() {
 	newUUID = uuid.New
 	osStat = os.Stat
 	megabyte = 1024 * 1024
+	scheduleCheckInterval = time.Minute
+	diskFreeSpace = diskFree
+	sameFilesystem = sameDevice
+	diskCheckInterval = 5 * time.Second
+	fileSync = (*os.File).Sync
+	dirSync = syncDir
 }
 
 // fileContainsContent checks if the bytes in `logfilepath` contains the expected content string.